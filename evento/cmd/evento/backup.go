@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eugenetolok/evento/internal/evento"
+)
+
+func runBackup(args []string) error {
+	var configPath, dir string
+	var noPhotos bool
+	fs := newFlagSet("backup", &configPath)
+	fs.StringVar(&dir, "dir", "backups", "folder where the backup is created")
+	fs.BoolVar(&noPhotos, "no-photos", false, "skip member photos")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := openDatabase(configPath); err != nil {
+		return err
+	}
+	target, err := evento.Backup(dir, !noPhotos)
+	if err != nil {
+		return err
+	}
+	fmt.Println("backup created:", target)
+	return nil
+}
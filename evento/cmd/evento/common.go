@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/eugenetolok/evento/internal/evento"
+	"gorm.io/gorm"
+)
+
+// openDatabase loads settings and connects to the database
+func openDatabase(configPath string) (*gorm.DB, error) {
+	if err := evento.LoadConfig(configPath); err != nil {
+		return nil, err
+	}
+	return evento.OpenDatabase()
+}
+
+// readPassword returns the flag value or the first line of stdin when fromStdin is set
+func readPassword(value string, fromStdin bool) (string, error) {
+	if !fromStdin {
+		return value, nil
+	}
+	if value != "" {
+		return "", errors.New("-password and -password-stdin are mutually exclusive")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// confirm asks for explicit "yes" unless assumeYes is set
+func confirm(question string, assumeYes bool) bool {
+	if assumeYes {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "%s Type \"yes\" to continue: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/eugenetolok/evento/internal/evento"
+	"gopkg.in/yaml.v3"
+)
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: evento config <validate|print> [flags]")
+	}
+	var configPath string
+	fs := newFlagSet("config "+args[0], &configPath)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := evento.LoadConfig(configPath); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "validate":
+		if err := evento.ValidateConfig(); err != nil {
+			return err
+		}
+		fmt.Println("configuration is valid")
+		return nil
+	case "print":
+		// effective settings after defaults and env overrides, secrets are redacted
+		settings, err := evento.RedactedSettings()
+		if err != nil {
+			return err
+		}
+		return yaml.NewEncoder(os.Stdout).Encode(settings)
+	}
+	return fmt.Errorf("unknown config command %q", args[0])
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/eugenetolok/evento/internal/evento/report"
+)
+
+func runExport(args []string) error {
+	var configPath, name, output string
+	fs := newFlagSet("export", &configPath)
+	fs.StringVar(&name, "report", "", "report name: "+strings.Join(report.ReportNames(), ", "))
+	fs.StringVar(&output, "o", "", "output xlsx file, stdout when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("-report is required")
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := report.Export(database, name, w); err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "report %s written to %s\n", name, output)
+	}
+	return nil
+}
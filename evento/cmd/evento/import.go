@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/eugenetolok/evento/internal/evento/company"
+	"github.com/eugenetolok/evento/internal/evento/member"
+	"github.com/eugenetolok/evento/internal/evento/user"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tealeg/xlsx/v3"
+	"gorm.io/gorm"
+)
+
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: evento import <members|companies> [flags]")
+	}
+	switch args[0] {
+	case "members":
+		return runImportMembers(args[1:])
+	case "companies":
+		return runImportCompanies(args[1:])
+	}
+	return fmt.Errorf("unknown import target %q", args[0])
+}
+
+func runImportMembers(args []string) error {
+	var configPath, file, companyRef, actorRef string
+	fs := newFlagSet("import members", &configPath)
+	fs.StringVar(&file, "file", "", "xlsx file made from the members template")
+	fs.StringVar(&companyRef, "company", "", "company id or INN")
+	fs.StringVar(&actorRef, "as", "", "username or id recorded in history, admin users may use hidden accreditations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if file == "" || companyRef == "" {
+		return errors.New("-file and -company are required")
+	}
+	xlsxFile, err := xlsx.OpenFile(file)
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	companyID, err := findCompanyID(database, companyRef)
+	if err != nil {
+		return err
+	}
+	actor, err := findActor(database, actorRef)
+	if err != nil {
+		return err
+	}
+	imported, err := member.ImportMembersXLSX(database, xlsxFile, member.MemberImportOptions{
+		CompanyID:     companyID,
+		UserID:        actor.ID,
+		IncludeHidden: actorRef == "" || actor.Role == "admin",
+		RequestID:     cliRequestID(),
+	})
+	if err != nil {
+		return importError(err)
+	}
+	fmt.Printf("imported members: %d\n", imported)
+	return nil
+}
+
+func runImportCompanies(args []string) error {
+	var configPath, file, editorRef string
+	fs := newFlagSet("import companies", &configPath)
+	fs.StringVar(&file, "file", "", "xlsx file made from the companies template")
+	fs.StringVar(&editorRef, "editor", "", "username or id of the editor assigned to the companies")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if file == "" {
+		return errors.New("-file is required")
+	}
+	xlsxFile, err := xlsx.OpenFile(file)
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	editor, err := findActor(database, editorRef)
+	if err != nil {
+		return err
+	}
+	imported, err := company.ImportCompaniesXLSX(database, xlsxFile, editor.ID, cliRequestID())
+	if err != nil {
+		return importError(err)
+	}
+	// generated credentials are shown only once, the passwords are stored hashed
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPANY\tINN\tUSERNAME\tPASSWORD")
+	for _, item := range imported {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Name, item.INN, item.Username, item.Password)
+	}
+	return w.Flush()
+}
+
+func findCompanyID(database *gorm.DB, ref string) (uuid.UUID, error) {
+	var found model.Company
+	query := database.Where("inn = ?", ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		query = database.Where("id = ?", id)
+	}
+	if err := query.First(&found).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, fmt.Errorf("company %q is not found", ref)
+		}
+		return uuid.Nil, err
+	}
+	return found.ID, nil
+}
+
+// findActor resolves an optional user reference, empty means no user
+func findActor(database *gorm.DB, ref string) (model.User, error) {
+	if ref == "" {
+		return model.User{}, nil
+	}
+	return user.FindUser(database, ref)
+}
+
+// cliRequestID marks history records written by one CLI run
+func cliRequestID() string {
+	return "cli-" + uuid.NewString()
+}
+
+// importError unwraps the user-facing message of validation errors
+func importError(err error) error {
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		return fmt.Errorf("%v", httpErr.Message)
+	}
+	return err
+}
@@ -2,53 +2,71 @@ package main
 
 import (
 	"flag"
-	"net/http"
-
-	"github.com/eugenetolok/evento/internal/evento"
-	"github.com/eugenetolok/evento/pkg/model"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
 )
 
-var flags model.Flags
+// command is a CLI subcommand, args exclude the command name
+type command struct {
+	summary string
+	run     func(args []string) error
+}
 
-func init() {
-	flag.BoolVar(&flags.Migrate, "migrate", false, "migrate tables")
-	flag.BoolVar(&flags.ShowYamlStruct, "yaml", false, "show yaml struct and exit")
-	flag.BoolVar(&flags.AddUser, "user", false, "add new user")
-	flag.BoolVar(&flags.DropTable, "drop", false, "WARNING: drops all tables!!!")
-	flag.StringVar(&flags.Port, "port", ":7777", "port of application, default is ':7777'")
-	flag.Parse()
+var commands = map[string]command{
+	"serve":   {"start HTTP server (default when no command is given)", runServe},
+	"migrate": {"create or update database tables", runMigrate},
+	"user":    {"manage users: add, list, reset-password, freeze, unfreeze", runUser},
+	"import":  {"import members or companies from xlsx", runImport},
+	"export":  {"export xlsx reports", runExport},
+	"backup":  {"copy database and photos to a backup folder", runBackup},
+	"config":  {"validate or print effective configuration", runConfig},
 }
 
 func main() {
-	// init config/db
-	evento.InitEvento(flags)
-
-	e := echo.New()
-
-	// Middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-	e.Use(middleware.Gzip())
-	e.Use(middleware.Secure())
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		Skipper:       middleware.DefaultSkipper,
-		AllowOrigins:  evento.GetCORSAllowOrigins(),
-		AllowMethods:  []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
-		ExposeHeaders: []string{"Content-Disposition", "Content-Type", "Content-Length"},
-	}))
-
-	// Static SPA serve
-	// e.Use(middleware.StaticWithConfig(middleware.StaticConfig{
-	// 	Root:   "dist",       // This is the path to your SPA build folder, the folder that is created from running "npm build"
-	// 	Index:  "index.html", // This is the default html page for your SPA
-	// 	Browse: false,
-	// 	HTML5:  true,
-	// }))
+	args := os.Args[1:]
+	// no command or only flags keeps the old "just start the server" behaviour
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
 
-	evento.Routes(e)
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: evento <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'evento <command> -h' for command flags.")
+}
 
-	// Start server
-	e.Logger.Fatal(e.Start(flags.Port))
+// newFlagSet creates a flag set with the common -config flag
+func newFlagSet(name string, configPath *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(configPath, "config", "app.yaml", "path to settings file, relative paths are resolved from the binary folder")
+	return fs
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/eugenetolok/evento/internal/evento"
+)
+
+func runMigrate(args []string) error {
+	var configPath string
+	var drop, yes bool
+	fs := newFlagSet("migrate", &configPath)
+	fs.BoolVar(&drop, "drop", false, "WARNING: drop all tables instead of migrating")
+	fs.BoolVar(&yes, "yes", false, "do not ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := openDatabase(configPath); err != nil {
+		return err
+	}
+	if drop {
+		if !confirm("All tables and data will be dropped.", yes) {
+			return errors.New("aborted")
+		}
+		if err := evento.DropTables(); err != nil {
+			return err
+		}
+		log.Println("All tables are dropped")
+		return nil
+	}
+	if err := evento.Migrate(); err != nil {
+		return err
+	}
+	log.Println("All tables are migrated")
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/eugenetolok/evento/internal/evento"
+)
+
+func runServe(args []string) error {
+	var configPath, port string
+	var watchInterval, shutdownTimeout time.Duration
+	fs := newFlagSet("serve", &configPath)
+	fs.StringVar(&port, "port", ":7777", "address to listen on")
+	fs.DurationVar(&watchInterval, "watch-interval", 5*time.Second, "how often the settings file is checked for changes, SIGHUP reloads immediately")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "how long to wait for in-flight requests and running jobs on SIGINT/SIGTERM")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := openDatabase(configPath); err != nil {
+		return err
+	}
+	if err := evento.PrepareServer(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	evento.WatchConfig(ctx, watchInterval)
+	evento.StartJobs(ctx)
+	e := evento.NewServer()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- e.Start(port)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			stopJobs(shutdownTimeout)
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	stop()
+	slog.Info("shutting down", "timeout", shutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	shutdownErr := e.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		slog.Error("http server shutdown failed", "error", shutdownErr)
+	}
+	if err := evento.StopJobs(shutdownCtx); err != nil {
+		slog.Error("jobs shutdown failed", "error", err)
+		return err
+	}
+	slog.Info("shutdown complete")
+	return shutdownErr
+}
+
+func stopJobs(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := evento.StopJobs(ctx); err != nil {
+		slog.Error("jobs shutdown failed", "error", err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eugenetolok/evento/internal/evento/user"
+	"github.com/eugenetolok/evento/pkg/utils"
+)
+
+func runUser(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: evento user <add|list|reset-password|freeze|unfreeze> [flags]")
+	}
+	switch args[0] {
+	case "add":
+		return runUserAdd(args[1:])
+	case "list":
+		return runUserList(args[1:])
+	case "reset-password":
+		return runUserResetPassword(args[1:])
+	case "freeze":
+		return runUserFreeze(args[1:], true)
+	case "unfreeze":
+		return runUserFreeze(args[1:], false)
+	}
+	return fmt.Errorf("unknown user command %q", args[0])
+}
+
+func runUserAdd(args []string) error {
+	var configPath, username, password, role string
+	var passwordStdin bool
+	fs := newFlagSet("user add", &configPath)
+	fs.StringVar(&username, "username", "", "login of the new user")
+	fs.StringVar(&password, "password", "", "password, generated and printed when empty")
+	fs.BoolVar(&passwordStdin, "password-stdin", false, "read password from the first line of stdin")
+	fs.StringVar(&role, "role", "", "one of: "+strings.Join(user.Roles, ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	password, err := readPassword(password, passwordStdin)
+	if err != nil {
+		return err
+	}
+	generated := password == ""
+	if generated {
+		password = utils.GenerateRandomString(16)
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	created, err := user.CreateUser(database, username, password, role)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created user %s (%s) id=%s\n", created.Username, created.Role, created.ID)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+func runUserList(args []string) error {
+	var configPath, role string
+	var asJSON bool
+	fs := newFlagSet("user list", &configPath)
+	fs.StringVar(&role, "role", "", "show only users with this role")
+	fs.BoolVar(&asJSON, "json", false, "print as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	users, err := user.ListUsers(database, role)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(users)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tROLE\tFROZEN")
+	for _, u := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", u.ID, u.Username, u.Role, u.Frozen)
+	}
+	return w.Flush()
+}
+
+func runUserResetPassword(args []string) error {
+	var configPath, ref, password string
+	var passwordStdin bool
+	fs := newFlagSet("user reset-password", &configPath)
+	fs.StringVar(&ref, "user", "", "username or id")
+	fs.StringVar(&password, "password", "", "new password, generated and printed when empty")
+	fs.BoolVar(&passwordStdin, "password-stdin", false, "read password from the first line of stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if ref == "" {
+		return errors.New("-user is required")
+	}
+	password, err := readPassword(password, passwordStdin)
+	if err != nil {
+		return err
+	}
+	generated := password == ""
+	if generated {
+		password = utils.GenerateRandomString(16)
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	found, err := user.FindUser(database, ref)
+	if err != nil {
+		return err
+	}
+	if err := user.SetUserPassword(database, found, password); err != nil {
+		return err
+	}
+	fmt.Printf("password updated for %s\n", found.Username)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+func runUserFreeze(args []string, frozen bool) error {
+	var configPath, ref string
+	name := "user unfreeze"
+	if frozen {
+		name = "user freeze"
+	}
+	fs := newFlagSet(name, &configPath)
+	fs.StringVar(&ref, "user", "", "username or id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if ref == "" {
+		return errors.New("-user is required")
+	}
+	database, err := openDatabase(configPath)
+	if err != nil {
+		return err
+	}
+	found, err := user.FindUser(database, ref)
+	if err != nil {
+		return err
+	}
+	if err := user.SetUserFrozen(database, found, frozen); err != nil {
+		return err
+	}
+	fmt.Printf("user %s frozen=%t\n", found.Username, frozen)
+	return nil
+}
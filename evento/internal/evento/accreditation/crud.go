@@ -3,6 +3,7 @@ package accreditation
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
@@ -75,12 +76,25 @@ func createAccreditation(c echo.Context) error {
 	if err := c.Bind(&accreditation); err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	if !badgeTemplateExists(accreditation.BadgeTemplateID) {
+		return c.String(http.StatusBadRequest, "Шаблон бейджа не найден")
+	}
 	if err := db.Create(&accreditation).Error; err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	return c.JSON(http.StatusCreated, accreditation)
 }
 
+// badgeTemplateExists checks the badge template of an accreditation, nil is the default template
+func badgeTemplateExists(id *uuid.UUID) bool {
+	if id == nil {
+		return true
+	}
+	var count int64
+	db.Model(&model.BadgeTemplate{}).Where("id = ?", *id).Count(&count)
+	return count > 0
+}
+
 func updateAccreditation(c echo.Context) error {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -100,13 +114,21 @@ func updateAccreditation(c echo.Context) error {
 		Position     uint        `json:"position"`
 		Hidden       bool        `json:"hidden"`
 		RequirePhoto bool        `json:"require_photo"`
+		Capacity     uint        `json:"capacity"`
 		GateIDs      []uuid.UUID `json:"gate_ids"`
+
+		RegistrationDeadline *time.Time `json:"registration_deadline"`
+		BadgeTemplateID      *uuid.UUID `json:"badge_template_id"`
 	}
 
 	if err := c.Bind(&input); err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if !badgeTemplateExists(input.BadgeTemplateID) {
+		return c.String(http.StatusBadRequest, "Шаблон бейджа не найден")
+	}
+
 	var newGates []model.Gate
 	if err := db.Where("id in (?)", input.GateIDs).Find(&newGates).Error; err != nil {
 		return c.String(http.StatusNotFound, "gates not found")
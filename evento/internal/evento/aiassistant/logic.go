@@ -12,6 +12,8 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/metrics"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/labstack/echo/v4"
 	_ "github.com/mattn/go-sqlite3"
@@ -172,8 +174,11 @@ func runQuery(c echo.Context) error {
 	llmCtx, llmCancel := context.WithTimeout(c.Request().Context(), time.Duration(config.LLMTimeoutMS)*time.Millisecond)
 	defer llmCancel()
 
+	generateStarted := time.Now()
 	plan, err := generateQueryPlan(llmCtx, config, request.Prompt, maxRows)
+	metrics.ObserveAICall("generate", generateStarted, err)
 	if err != nil {
+		logger.FromEcho(c).Warn("ai query generation failed", "duration_ms", time.Since(generateStarted).Milliseconds(), "error", err)
 		return c.JSON(http.StatusBadGateway, map[string]string{
 			"error": fmt.Sprintf("ai generation failed: %s", err.Error()),
 		})
@@ -188,12 +193,16 @@ func runQuery(c echo.Context) error {
 	}
 	plan.SQL = safeSQL
 
+	queryStarted := time.Now()
 	rows, columns, err := executeReadOnlyQuery(c.Request().Context(), safeSQL, config.QueryTimeoutMS, maxRows)
+	metrics.ObserveAICall("query", queryStarted, err)
 	if err != nil {
+		logger.FromEcho(c).Warn("ai query execution failed", "sql", safeSQL, "error", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": fmt.Sprintf("query execution failed: %s", err.Error()),
 		})
 	}
+	logger.FromEcho(c).Info("ai query executed", "sql", safeSQL, "rows", len(rows), "duration_ms", time.Since(queryStarted).Milliseconds())
 	if humanReadable {
 		columns, rows = applyHumanReadableResult(columns, rows)
 	}
@@ -278,12 +287,16 @@ func exportQuery(c echo.Context) error {
 		})
 	}
 
+	queryStarted := time.Now()
 	rows, columns, err := executeReadOnlyQuery(c.Request().Context(), safeSQL, config.QueryTimeoutMS, maxRows)
+	metrics.ObserveAICall("query", queryStarted, err)
 	if err != nil {
+		logger.FromEcho(c).Warn("ai query execution failed", "sql", safeSQL, "error", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": fmt.Sprintf("query execution failed: %s", err.Error()),
 		})
 	}
+	logger.FromEcho(c).Info("ai query executed", "sql", safeSQL, "rows", len(rows), "duration_ms", time.Since(queryStarted).Milliseconds())
 	if humanReadable {
 		columns, rows = applyHumanReadableResult(columns, rows)
 	}
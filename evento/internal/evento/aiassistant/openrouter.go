@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 )
 
@@ -79,6 +80,9 @@ func generateQueryPlan(ctx context.Context, settings model.AIAssistantSettings,
 	}
 	httpRequest.Header.Set("Content-Type", "application/json")
 	httpRequest.Header.Set("Authorization", "Bearer "+settings.OpenRouterAPIKey)
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		httpRequest.Header.Set(logger.HeaderRequestID, requestID)
+	}
 	if referer := strings.TrimSpace(settings.OpenRouterReferer); referer != "" {
 		httpRequest.Header.Set("HTTP-Referer", referer)
 	}
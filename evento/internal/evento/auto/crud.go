@@ -70,7 +70,6 @@ func createAuto(c echo.Context) error {
 		if user.CompanyID == uuid.Nil {
 			return c.String(http.StatusBadRequest, `Компания не найдена`)
 		}
-		fmt.Println("jwt userID", userID, "user.CompanyID", user.CompanyID)
 		companyID = user.CompanyID
 	}
 	// get member company which will be assigned
@@ -1,6 +1,7 @@
 package auto
 
 import (
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
@@ -16,6 +17,7 @@ func logAutoHistory(tx *gorm.DB, c echo.Context, autoID uuid.UUID, changeType st
 		UserID:     userID,
 		ChangeType: changeType,
 		Details:    details,
+		RequestID:  logger.EchoRequestID(c),
 	}
 	return tx.Create(&history).Error
 }
@@ -0,0 +1,72 @@
+package evento
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Backup writes a consistent copy of the database and the photo storage into dir/evento-<timestamp>.
+// Returns the created backup directory.
+func Backup(dir string, withPhotos bool) (string, error) {
+	target := filepath.Join(dir, "evento-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return "", err
+	}
+	dbCopy := filepath.Join(target, filepath.Base(appSettings.SiteSettings.DBPath))
+	// VACUUM INTO produces a consistent snapshot without stopping the server
+	if err := db.Exec("VACUUM INTO ?", dbCopy).Error; err != nil {
+		return "", fmt.Errorf("database backup failed: %w", err)
+	}
+	if !withPhotos {
+		return target, nil
+	}
+	photos := PhotoStorageDir()
+	if _, err := os.Stat(photos); os.IsNotExist(err) {
+		return target, nil
+	}
+	if err := copyDir(photos, filepath.Join(target, "photos")); err != nil {
+		return "", fmt.Errorf("photos backup failed: %w", err)
+	}
+	return target, nil
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,101 @@
+package badge
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/qr"
+)
+
+// EncodeBarcode encodes content as code128 (default), qr or datamatrix
+func EncodeBarcode(format, content string) (barcode.Barcode, error) {
+	switch strings.ToLower(format) {
+	case "", "code128":
+		return code128.Encode(content)
+	case "qr":
+		return qr.Encode(content, qr.M, qr.Auto)
+	case "datamatrix":
+		return datamatrix.Encode(content)
+	}
+	return nil, fmt.Errorf("unknown barcode format %s", format)
+}
+
+// BarcodePNG renders the code at least width x height pixels, smaller sizes are raised to one pixel per module
+func BarcodePNG(format, content string, width, height int) ([]byte, error) {
+	code, err := EncodeBarcode(format, content)
+	if err != nil {
+		return nil, err
+	}
+	bounds := code.Bounds()
+	scaled, err := barcode.Scale(code, maxInt(bounds.Dx(), width), maxInt(bounds.Dy(), height))
+	if err != nil {
+		return nil, err
+	}
+	// barcodes are 16 bit gray which gofpdf can not embed
+	gray := image.NewGray(scaled.Bounds())
+	draw.Draw(gray, gray.Bounds(), scaled, scaled.Bounds().Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BarcodeSVG renders the code as an svg of width x height, modules are merged into horizontal runs.
+// The view box is the module grid, so the one row of code128 is stretched over the height
+func BarcodeSVG(format, content string, width, height int) ([]byte, error) {
+	code, err := EncodeBarcode(format, content)
+	if err != nil {
+		return nil, err
+	}
+	bounds := code.Bounds()
+	rows := bounds.Dy()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" preserveAspectRatio="none" shape-rendering="crispEdges">`,
+		width, height, bounds.Dx(), rows)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, bounds.Dx(), rows)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; {
+			if !isDark(code, x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < bounds.Max.X && isDark(code, x, y) {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start-bounds.Min.X, y-bounds.Min.Y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes(), nil
+}
+
+func isDark(code barcode.Barcode, x, y int) bool {
+	r, _, _, _ := code.At(x, y).RGBA()
+	return r < 0x8000
+}
+
+// BarcodeDataURL is BarcodePNG as a data url for templates and clients
+func BarcodeDataURL(format, content string, width, height int) (string, error) {
+	image, err := BarcodePNG(format, content, width, height)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(image), nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package badge
 
 import (
+	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	echojwt "github.com/labstack/echo-jwt"
 	"github.com/labstack/echo/v4"
@@ -8,14 +9,21 @@ import (
 )
 
 var db *gorm.DB
+var photoDir string
+var frontendSettings func() model.FrontendSettings
 
 // InitBadges entry point of badges
-func InitBadges(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config) {
+func InitBadges(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, photoDirIn string, frontendSettingsIn func() model.FrontendSettings) {
 	db = dbInstance
+	photoDir = photoDirIn
+	frontendSettings = frontendSettingsIn
 	g.Use(echojwt.WithConfig(jwtConfig))
 	g.POST("", createBadgeTemplate, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("", getBadgeTemplates, utils.RoleMiddleware([]string{"admin"}))
+	g.POST("/preview", previewBadgeTemplate, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("/:id", getBadgeTemplate, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
 	g.PUT("/:id", updateBadgeTemplate, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
 	g.DELETE("/:id", deleteBadgeTemplate, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/:id/versions", getBadgeTemplateVersions, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.POST("/:id/rollback", rollbackBadgeTemplate, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
 }
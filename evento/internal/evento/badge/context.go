@@ -0,0 +1,170 @@
+package badge
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eugenetolok/evento/internal/evento/credential"
+	"github.com/eugenetolok/evento/internal/evento/frontendsettings"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// templateImageSize is the pixel size of barcode images put into templates
+const templateImageSize = 300
+
+// Festival is the event the badges are printed for, taken from frontend settings
+type Festival struct {
+	Name string
+	Year string
+	City string
+}
+
+// TemplateData is the context of badge templates: {{.Member.Surname}}, {{.Company.Name}},
+// {{range .Events}}, {{range .Gates}}, {{.Festival.Name}}, {{.PhotoBase64}}, {{.BarcodeImage "qr"}}, {{.Credential}}
+type TemplateData struct {
+	Member        model.Member
+	Company       model.Company
+	Accreditation model.Accreditation
+	// Events of the member sorted by start
+	Events []model.Event
+	// Gates of the member and the accreditation sorted by position
+	Gates    []model.Gate
+	PhotoURL string
+	Festival Festival
+}
+
+// PreloadTemplateData preloads the relations badge templates use, rendering a batch then needs no query per member
+func PreloadTemplateData(query *gorm.DB) *gorm.DB {
+	return query.Preload("Company").Preload("Accreditation.Gates").Preload("Gates").Preload("Events")
+}
+
+// newTemplateData fills the context, relations which are not preloaded are read from the database
+func newTemplateData(member model.Member) (TemplateData, error) {
+	data := TemplateData{Member: member, Company: member.Company, Accreditation: member.Accreditation, Events: member.Events}
+	if db != nil {
+		if data.Company.ID == uuid.Nil && member.CompanyID != uuid.Nil {
+			if err := db.First(&data.Company, member.CompanyID).Error; err != nil {
+				return data, fmt.Errorf("company of the member: %w", err)
+			}
+		}
+		if data.Accreditation.ID == uuid.Nil && member.AccreditationID != uuid.Nil {
+			if err := db.Preload("Gates").First(&data.Accreditation, member.AccreditationID).Error; err != nil {
+				return data, fmt.Errorf("accreditation of the member: %w", err)
+			}
+		}
+		if data.Events == nil && member.ID != uuid.Nil {
+			if err := db.Model(&member).Association("Events").Find(&data.Events); err != nil {
+				return data, fmt.Errorf("events of the member: %w", err)
+			}
+		}
+	}
+	sort.SliceStable(data.Events, func(i, j int) bool { return data.Events[i].TimeStart.Before(data.Events[j].TimeStart) })
+	data.Gates = memberGates(member.Gates, data.Accreditation.Gates)
+	if member.PhotoFilename != "" && member.ID != uuid.Nil {
+		data.PhotoURL = "/api/members/" + member.ID.String() + "/photo"
+	}
+	if frontendSettings != nil {
+		settings := frontendsettings.Effective(frontendSettings())
+		data.Festival = Festival{Name: settings.Name, Year: settings.Year, City: settings.City}
+	}
+	return data, nil
+}
+
+// memberGates merges gates of the member and the accreditation without duplicates
+func memberGates(lists ...[]model.Gate) []model.Gate {
+	seen := map[string]bool{}
+	var gates []model.Gate
+	for _, list := range lists {
+		for _, gate := range list {
+			key := gate.ID.String() + gate.ShortName
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			gates = append(gates, gate)
+		}
+	}
+	sort.SliceStable(gates, func(i, j int) bool { return gates[i].Position < gates[j].Position })
+	return gates
+}
+
+// PhotoBase64 returns the member photo as a data url, empty without a photo
+func (d TemplateData) PhotoBase64() string {
+	if d.Member.PhotoFilename == "" || photoDir == "" {
+		return ""
+	}
+	photo, err := os.ReadFile(filepath.Join(photoDir, d.Member.PhotoFilename))
+	if err != nil {
+		return ""
+	}
+	mime := "image/jpeg"
+	if strings.EqualFold(filepath.Ext(d.Member.PhotoFilename), ".png") {
+		mime = "image/png"
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(photo)
+}
+
+// BarcodeImage returns the member barcode as a png data url, format is code128, qr or datamatrix
+func (d TemplateData) BarcodeImage(format string) (string, error) {
+	if d.Member.Barcode == "" {
+		return "", nil
+	}
+	height := templateImageSize
+	if format == "" || format == "code128" {
+		height = templateImageSize / 3
+	}
+	return BarcodeDataURL(format, d.Member.Barcode, templateImageSize, height)
+}
+
+// Credential returns the signed credential of the member for qr elements: {"type":"qr","content":"{{.Credential}}"},
+// the sample member has none
+func (d TemplateData) Credential() (string, error) {
+	if d.Member.ID == uuid.Nil || db == nil {
+		return "", nil
+	}
+	code, _, err := credential.Issue(d.Member.ID)
+	return code, err
+}
+
+var templateFuncs = template.FuncMap{
+	"upper":         strings.ToUpper,
+	"lower":         strings.ToLower,
+	"transliterate": utils.Transliterate,
+	"truncate":      truncate,
+	"date":          formatDate,
+}
+
+// truncate keeps the first n letters: {{.Company.Name | truncate 20}}
+func truncate(n int, s string) string {
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// formatDate formats time.Time or *time.Time with a go layout: {{.Member.Birth | date "02.01.2006"}}
+func formatDate(layout string, value interface{}) (string, error) {
+	switch t := value.(type) {
+	case time.Time:
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil || t.IsZero() {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("date: unsupported value %T", value)
+}
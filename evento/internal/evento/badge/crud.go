@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -35,6 +36,11 @@ func createBadgeTemplate(c echo.Context) error {
 	if err := c.Bind(&template); err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	template.ID = uuid.Nil
+	if err := ValidateTemplate(db, uuid.Nil, template.TemplateJSON); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	userID, _ := utils.GetUser(c)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// If this new template is set to default, unset all others first
@@ -49,7 +55,7 @@ func createBadgeTemplate(c echo.Context) error {
 			return err
 		}
 
-		return nil
+		return saveTemplateVersion(tx, &template, userID, "")
 	})
 
 	if err != nil {
@@ -66,10 +72,20 @@ func updateBadgeTemplate(c echo.Context) error {
 		return c.String(http.StatusNotFound, "Template not found")
 	}
 
-	var input model.BadgeTemplate
+	var input struct {
+		model.BadgeTemplate
+		Comment string `json:"comment"`
+	}
 	if err := c.Bind(&input); err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+	changed := input.Name != template.Name || input.TemplateJSON != template.TemplateJSON
+	if changed {
+		if err := ValidateTemplate(db, template.ID, input.TemplateJSON); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+	userID, _ := utils.GetUser(c)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// If this template is being set to default, unset all others first
@@ -86,8 +102,10 @@ func updateBadgeTemplate(c echo.Context) error {
 		if err := tx.Save(&template).Error; err != nil {
 			return err
 		}
-
-		return nil
+		if !changed {
+			return nil
+		}
+		return saveTemplateVersion(tx, &template, userID, input.Comment)
 	})
 
 	if err != nil {
@@ -97,9 +115,19 @@ func updateBadgeTemplate(c echo.Context) error {
 	return c.JSON(http.StatusOK, template)
 }
 
+// deleteBadgeTemplate removes the template, accreditations and companies using it fall back to the default
 func deleteBadgeTemplate(c echo.Context) error {
 	id, _ := uuid.Parse(c.Param("id"))
-	if err := db.Delete(&model.BadgeTemplate{}, id).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Accreditation{}).Where("badge_template_id = ?", id).Update("badge_template_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("badge_template_id = ?", id).Delete(&model.CompanyBadgeTemplate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.BadgeTemplate{}, id).Error
+	})
+	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
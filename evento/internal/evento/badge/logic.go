@@ -11,15 +11,16 @@ import (
 // ProcessBadgeTemplate takes a member and a template, and returns the final JSON payload.
 func ProcessBadgeTemplate(member model.Member, templateJSON string) ([]byte, error) {
 	// 1. Use text/template to replace placeholders
-	tmpl, err := template.New("badge").Parse(templateJSON)
+	tmpl, err := template.New("badge").Funcs(templateFuncs).Parse(templateJSON)
 	if err != nil {
 		return nil, err
 	}
 
-	var processedBuf bytes.Buffer
-	data := map[string]interface{}{
-		"Member": member,
+	data, err := newTemplateData(member)
+	if err != nil {
+		return nil, err
 	}
+	var processedBuf bytes.Buffer
 	if err := tmpl.Execute(&processedBuf, data); err != nil {
 		return nil, err
 	}
@@ -40,18 +41,11 @@ func ProcessBadgeTemplate(member model.Member, templateJSON string) ([]byte, err
 	var finalElements []interface{}
 	memberGateShortNames := make(map[string]bool)
 	// Collect all gate short names the member has access to
-	for _, g := range member.Gates {
+	for _, g := range data.Gates {
 		if g.ShortName != "" {
 			memberGateShortNames[g.ShortName] = true
 		}
 	}
-	if member.Accreditation.Gates != nil {
-		for _, g := range member.Accreditation.Gates {
-			if g.ShortName != "" {
-				memberGateShortNames[g.ShortName] = true
-			}
-		}
-	}
 
 	for _, el := range elements {
 		elMap, isMap := el.(map[string]interface{})
@@ -60,11 +54,20 @@ func ProcessBadgeTemplate(member model.Member, templateJSON string) ([]byte, err
 			continue
 		}
 
-		if elType, ok := elMap["type"].(string); ok && elType == "zones" {
+		switch elMap["type"] {
+		case "zones":
 			// This is our special zones element, replace it
 			zoneElements := generateZoneElements(elMap, memberGateShortNames)
 			finalElements = append(finalElements, zoneElements...)
-		} else {
+		case "events":
+			finalElements = append(finalElements, generateEventElements(elMap, data.Events)...)
+		case "qr":
+			qrElement, err := generateQRElement(elMap, member.Barcode)
+			if err != nil {
+				return nil, err
+			}
+			finalElements = append(finalElements, qrElement)
+		default:
 			// This is a regular element, add it back
 			finalElements = append(finalElements, el)
 		}
@@ -131,3 +134,62 @@ func generateZoneElements(zoneTemplate map[string]interface{}, memberGates map[s
 
 	return generatedElements
 }
+
+// generateEventElements lists events of the member as text elements, one per line:
+// {"type": "events", "x", "y", "line_height", "date_format", "text_properties"}
+func generateEventElements(eventsTemplate map[string]interface{}, events []model.Event) []interface{} {
+	var generatedElements []interface{}
+	textProps, _ := eventsTemplate["text_properties"].(map[string]interface{})
+	x, _ := eventsTemplate["x"].(float64)
+	y, _ := eventsTemplate["y"].(float64)
+	lineHeight, _ := eventsTemplate["line_height"].(float64)
+	if lineHeight <= 0 {
+		lineHeight = 5
+	}
+	dateFormat, _ := eventsTemplate["date_format"].(string)
+
+	for i, event := range events {
+		content := event.Name
+		if dateFormat != "" && !event.TimeStart.IsZero() {
+			content += " " + event.TimeStart.Format(dateFormat)
+		}
+		newTextProps := make(map[string]interface{})
+		for k, v := range textProps {
+			newTextProps[k] = v
+		}
+		newTextProps["content"] = content
+		generatedElements = append(generatedElements, map[string]interface{}{
+			"type":   "text",
+			"x":      x,
+			"y":      y + float64(i)*lineHeight,
+			"text":   newTextProps,
+			"valign": "T",
+		})
+	}
+	return generatedElements
+}
+
+// generateQRElement turns {"type": "qr", "content"} into an image element with the png data url,
+// without content the member barcode is encoded
+func generateQRElement(qrTemplate map[string]interface{}, barcode string) (map[string]interface{}, error) {
+	element := make(map[string]interface{}, len(qrTemplate)+2)
+	for k, v := range qrTemplate {
+		element[k] = v
+	}
+	content, _ := qrTemplate["content"].(string)
+	if content == "" {
+		content = barcode
+	}
+	element["type"] = "image"
+	element["format"] = "qr"
+	element["content"] = content
+	if content == "" {
+		return element, nil
+	}
+	data, err := BarcodeDataURL("qr", content, templateImageSize, templateImageSize)
+	if err != nil {
+		return nil, err
+	}
+	element["data"] = data
+	return element, nil
+}
@@ -0,0 +1,362 @@
+package badge
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Page sizes in millimetres, "badge" prints one badge per page of the badge size
+var pageSizes = map[string][2]float64{
+	"A3": {297, 420},
+	"A4": {210, 297},
+	"A5": {148, 210},
+	"A6": {105, 148},
+}
+
+// barcodeDotsPerMM is the raster resolution of barcodes, about 300 dpi
+const barcodeDotsPerMM = 12
+
+// PDFLayout places badges on pages, all sizes are in millimetres.
+// Zero values are taken from badge settings, zero columns and rows fit as many badges as possible
+type PDFLayout struct {
+	PageSize    string  `json:"page_size" query:"page_size"`
+	Landscape   bool    `json:"landscape" query:"landscape"`
+	PageWidth   float64 `json:"page_width" query:"page_width"`
+	PageHeight  float64 `json:"page_height" query:"page_height"`
+	BadgeWidth  float64 `json:"badge_width" query:"badge_width"`
+	BadgeHeight float64 `json:"badge_height" query:"badge_height"`
+	Margin      float64 `json:"margin" query:"margin"`
+	Gap         float64 `json:"gap" query:"gap"`
+	Columns     int     `json:"columns" query:"columns"`
+	Rows        int     `json:"rows" query:"rows"`
+}
+
+// PDFBadge is a processed badge payload and the member photo path, empty when there is no photo
+type PDFBadge struct {
+	Payload []byte
+	Photo   string
+}
+
+// ResolvePDFLayout fills the layout from settings and counts badges per page
+func ResolvePDFLayout(layout PDFLayout, settings model.BadgeSettings) (PDFLayout, error) {
+	if layout.PageSize == "" {
+		layout.PageSize = settings.PageSize
+	}
+	if layout.BadgeWidth <= 0 {
+		layout.BadgeWidth = settings.BadgeWidth
+	}
+	if layout.BadgeHeight <= 0 {
+		layout.BadgeHeight = settings.BadgeHeight
+	}
+	if layout.BadgeWidth <= 0 || layout.BadgeHeight <= 0 {
+		return layout, errors.New("Не указан размер бейджа")
+	}
+	switch size := strings.ToUpper(layout.PageSize); size {
+	case "BADGE":
+		layout.PageSize = "badge"
+		layout.PageWidth, layout.PageHeight = layout.BadgeWidth, layout.BadgeHeight
+		layout.Margin, layout.Gap, layout.Columns, layout.Rows = 0, 0, 1, 1
+		return layout, nil
+	case "CUSTOM":
+		layout.PageSize = "custom"
+		if layout.PageWidth <= 0 || layout.PageHeight <= 0 {
+			return layout, errors.New("Для страницы custom укажите page_width и page_height")
+		}
+	default:
+		dimensions, ok := pageSizes[size]
+		if !ok {
+			return layout, fmt.Errorf("Неизвестный формат страницы %s", layout.PageSize)
+		}
+		layout.PageSize = size
+		layout.PageWidth, layout.PageHeight = dimensions[0], dimensions[1]
+	}
+	if layout.Landscape {
+		layout.PageWidth, layout.PageHeight = layout.PageHeight, layout.PageWidth
+	}
+	if layout.Margin <= 0 {
+		layout.Margin = settings.Margin
+	}
+	if layout.Gap <= 0 {
+		layout.Gap = settings.Gap
+	}
+	fitColumns := int((layout.PageWidth - 2*layout.Margin + layout.Gap) / (layout.BadgeWidth + layout.Gap))
+	fitRows := int((layout.PageHeight - 2*layout.Margin + layout.Gap) / (layout.BadgeHeight + layout.Gap))
+	if fitColumns < 1 || fitRows < 1 {
+		return layout, errors.New("Бейдж не помещается на страницу")
+	}
+	if layout.Columns <= 0 || layout.Columns > fitColumns {
+		layout.Columns = fitColumns
+	}
+	if layout.Rows <= 0 || layout.Rows > fitRows {
+		layout.Rows = fitRows
+	}
+	return layout, nil
+}
+
+// RenderPDF draws badges on pages of the resolved layout. Text uses TTF fonts from settings.FontsDir,
+// when a font is missing the default font is used, without it text is transliterated to a core font
+func RenderPDF(w io.Writer, badges []PDFBadge, layout PDFLayout, settings model.BadgeSettings) error {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "mm",
+		Size:    gofpdf.SizeType{Wd: layout.PageWidth, Ht: layout.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	r := &pdfRenderer{pdf: pdf, fontsDir: settings.FontsDir, defaultFont: settings.DefaultFont, fonts: map[string]bool{}}
+
+	perPage := layout.Columns * layout.Rows
+	for i, item := range badges {
+		slot := i % perPage
+		if slot == 0 {
+			pdf.AddPage()
+		}
+		x := layout.Margin + float64(slot%layout.Columns)*(layout.BadgeWidth+layout.Gap)
+		y := layout.Margin + float64(slot/layout.Columns)*(layout.BadgeHeight+layout.Gap)
+		if err := r.drawBadge(item, x, y, i); err != nil {
+			return err
+		}
+		if err := pdf.Error(); err != nil {
+			return err
+		}
+	}
+	return pdf.Output(w)
+}
+
+type pdfRenderer struct {
+	pdf         *gofpdf.Fpdf
+	fontsDir    string
+	defaultFont string
+	fonts       map[string]bool
+	images      int
+}
+
+func (r *pdfRenderer) drawBadge(item PDFBadge, originX, originY float64, index int) error {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(item.Payload, &payload); err != nil {
+		return fmt.Errorf("badge %d: %w", index, err)
+	}
+	elements, _ := payload["elements"].([]interface{})
+	for _, raw := range elements {
+		el, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		x := originX + number(el, "x")
+		y := originY + number(el, "y")
+		var err error
+		switch str(el, "type") {
+		case "text":
+			r.drawText(el, x, y)
+		case "barcode":
+			err = r.drawBarcode(el, str(el, "format"), x, y)
+		case "photo":
+			err = r.drawPhoto(item.Photo, el, x, y)
+		case "image":
+			err = r.drawImage(el, x, y)
+		}
+		if err != nil {
+			return fmt.Errorf("badge %d: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// drawText renders {"text": {"content", "font_file", "font_size", "color", "align"}, "width", "valign"}
+func (r *pdfRenderer) drawText(el map[string]interface{}, x, y float64) {
+	props, _ := el["text"].(map[string]interface{})
+	if props == nil {
+		props = el
+	}
+	content := str(props, "content")
+	if content == "" {
+		return
+	}
+	size := number(props, "font_size")
+	if size <= 0 {
+		size = number(props, "size")
+	}
+	if size <= 0 {
+		size = 12
+	}
+	if !r.setFont(str(props, "font_file"), size) {
+		content = utils.Transliterate(content)
+	}
+	red, green, blue := parseColor(str(props, "color"))
+	r.pdf.SetTextColor(red, green, blue)
+
+	align := strings.ToUpper(str(props, "align"))
+	if align != "C" && align != "R" {
+		align = "L"
+	}
+	valign := strings.ToUpper(str(el, "valign"))
+	if valign != "M" && valign != "B" && valign != "A" {
+		valign = "T"
+	}
+	width := number(el, "width")
+	if width <= 0 {
+		width = number(props, "width")
+	}
+	if width <= 0 {
+		width = r.pdf.GetStringWidth(content)
+	}
+	_, lineHeight := r.pdf.GetFontSize()
+	r.pdf.SetXY(x, y)
+	r.pdf.CellFormat(width, lineHeight, content, "", 0, align+valign, false, 0, "")
+}
+
+// setFont selects the font file or the default one, false means only a core font is available
+func (r *pdfRenderer) setFont(file string, size float64) bool {
+	for _, candidate := range []string{file, r.defaultFont} {
+		if candidate == "" {
+			continue
+		}
+		candidate = filepath.Base(candidate)
+		if !r.fonts[candidate] {
+			font, err := os.ReadFile(filepath.Join(r.fontsDir, candidate))
+			if err != nil {
+				continue
+			}
+			r.pdf.AddUTF8FontFromBytes(candidate, "", font)
+			r.fonts[candidate] = true
+		}
+		r.pdf.SetFont(candidate, "", size)
+		return true
+	}
+	r.pdf.SetFont("Helvetica", "", size)
+	return false
+}
+
+// drawBarcode renders {"format": "code128|qr|datamatrix", "content", "width", "height"}
+func (r *pdfRenderer) drawBarcode(el map[string]interface{}, format string, x, y float64) error {
+	content := str(el, "content")
+	if content == "" {
+		return nil
+	}
+	width, height := number(el, "width"), number(el, "height")
+	if width <= 0 {
+		width = 30
+	}
+	if height <= 0 {
+		height = width
+		if format == "code128" || format == "" {
+			height = width / 3
+		}
+	}
+	image, err := BarcodePNG(format, content, int(width*barcodeDotsPerMM), int(height*barcodeDotsPerMM))
+	if err != nil {
+		return err
+	}
+	r.placeImage(bytes.NewReader(image), "PNG", x, y, width, height)
+	return nil
+}
+
+// drawPhoto renders the member photo into {"width", "height"}, badges without a photo leave the place empty
+func (r *pdfRenderer) drawPhoto(path string, el map[string]interface{}, x, y float64) error {
+	if path == "" {
+		return nil
+	}
+	imageType := imageTypeFromName(path)
+	if imageType == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	r.placeImage(file, imageType, x, y, number(el, "width"), number(el, "height"))
+	return nil
+}
+
+// drawImage renders a base64 png/jpeg from {"data"}, data urls are accepted
+func (r *pdfRenderer) drawImage(el map[string]interface{}, x, y float64) error {
+	data := str(el, "data")
+	imageType := "PNG"
+	if strings.HasPrefix(data, "data:") {
+		header, encoded, _ := strings.Cut(data, ",")
+		if strings.Contains(header, "jpeg") || strings.Contains(header, "jpg") {
+			imageType = "JPG"
+		}
+		data = encoded
+	}
+	if data == "" {
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("image: %w", err)
+	}
+	if len(decoded) > 2 && decoded[0] == 0xFF && decoded[1] == 0xD8 {
+		imageType = "JPG"
+	}
+	r.placeImage(bytes.NewReader(decoded), imageType, x, y, number(el, "width"), number(el, "height"))
+	return nil
+}
+
+// placeImage registers the image under a unique name, zero width or height keeps the aspect ratio
+func (r *pdfRenderer) placeImage(reader io.Reader, imageType string, x, y, width, height float64) {
+	r.images++
+	name := "image" + strconv.Itoa(r.images)
+	options := gofpdf.ImageOptions{ImageType: imageType}
+	r.pdf.RegisterImageOptionsReader(name, options, reader)
+	r.pdf.ImageOptions(name, x, y, width, height, false, options, 0, "")
+}
+
+func imageTypeFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg":
+		return "JPG"
+	case ".png":
+		return "PNG"
+	}
+	return ""
+}
+
+func number(m map[string]interface{}, key string) float64 {
+	switch value := m[key].(type) {
+	case float64:
+		return value
+	case string:
+		parsed, _ := strconv.ParseFloat(value, 64)
+		return parsed
+	}
+	return 0
+}
+
+func str(m map[string]interface{}, key string) string {
+	switch value := m[key].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// parseColor reads #rrggbb, anything else is black
+func parseColor(color string) (int, int, int) {
+	color = strings.TrimPrefix(color, "#")
+	if len(color) != 6 {
+		return 0, 0, 0
+	}
+	value, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return int(value >> 16 & 0xFF), int(value >> 8 & 0xFF), int(value & 0xFF)
+}
@@ -0,0 +1,170 @@
+package badge
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// validationSamples is how many members a template is rendered against before saving
+const validationSamples = 5
+
+// TemplateResolver picks the badge template of a member: company override for the accreditation,
+// company override for all accreditations, template of the accreditation, default template.
+// Lookups are cached, use one resolver per request
+type TemplateResolver struct {
+	db             *gorm.DB
+	templates      map[uuid.UUID]model.BadgeTemplate
+	accreditations map[uuid.UUID]*uuid.UUID
+	companies      map[uuid.UUID][]model.CompanyBadgeTemplate
+}
+
+// NewTemplateResolver returns a resolver reading from database
+func NewTemplateResolver(database *gorm.DB) *TemplateResolver {
+	return &TemplateResolver{
+		db:             database,
+		templates:      map[uuid.UUID]model.BadgeTemplate{},
+		accreditations: map[uuid.UUID]*uuid.UUID{},
+		companies:      map[uuid.UUID][]model.CompanyBadgeTemplate{},
+	}
+}
+
+// ForMember returns the template of the member, gorm.ErrRecordNotFound when there is none
+func (r *TemplateResolver) ForMember(member model.Member) (model.BadgeTemplate, error) {
+	overrides, ok := r.companies[member.CompanyID]
+	if !ok {
+		if err := r.db.Where("company_id = ?", member.CompanyID).Find(&overrides).Error; err != nil {
+			return model.BadgeTemplate{}, err
+		}
+		r.companies[member.CompanyID] = overrides
+	}
+	var companyWide *uuid.UUID
+	for i := range overrides {
+		if overrides[i].AccreditationID == member.AccreditationID {
+			return r.template(overrides[i].BadgeTemplateID)
+		}
+		if overrides[i].AccreditationID == uuid.Nil {
+			companyWide = &overrides[i].BadgeTemplateID
+		}
+	}
+	if companyWide != nil {
+		return r.template(*companyWide)
+	}
+
+	templateID, ok := r.accreditations[member.AccreditationID]
+	if !ok {
+		var accreditation model.Accreditation
+		err := r.db.Select("id", "badge_template_id").First(&accreditation, member.AccreditationID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.BadgeTemplate{}, err
+		}
+		templateID = accreditation.BadgeTemplateID
+		r.accreditations[member.AccreditationID] = templateID
+	}
+	if templateID != nil {
+		return r.template(*templateID)
+	}
+	return r.template(uuid.Nil)
+}
+
+// template loads a template by id, uuid.Nil is the default template
+func (r *TemplateResolver) template(id uuid.UUID) (model.BadgeTemplate, error) {
+	if template, ok := r.templates[id]; ok {
+		return template, nil
+	}
+	var template model.BadgeTemplate
+	query := r.db.Where("id = ?", id)
+	if id == uuid.Nil {
+		query = r.db.Where("is_default = ?", true)
+	}
+	if err := query.First(&template).Error; err != nil {
+		return template, err
+	}
+	r.templates[id] = template
+	return template, nil
+}
+
+// ValidateTemplate renders the template against members who use it, or any members,
+// or a sample member when there are none
+func ValidateTemplate(database *gorm.DB, templateID uuid.UUID, templateJSON string) error {
+	members, err := sampleMembers(database, templateID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if _, err := ProcessBadgeTemplate(member, templateJSON); err != nil {
+			return fmt.Errorf("Шаблон не отображается для участника %s %s: %w", member.Surname, member.Name, err)
+		}
+	}
+	return nil
+}
+
+func sampleMembers(database *gorm.DB, templateID uuid.UUID) ([]model.Member, error) {
+	var members []model.Member
+	query := func() *gorm.DB {
+		return PreloadTemplateData(database).Order("created_at DESC").Limit(validationSamples)
+	}
+	if templateID != uuid.Nil {
+		assigned := database.Model(&model.Member{}).Select("members.id").
+			Joins("LEFT JOIN accreditations ON accreditations.id = members.accreditation_id").
+			Where(`accreditations.badge_template_id = ? OR members.company_id IN (?)`, templateID,
+				database.Model(&model.CompanyBadgeTemplate{}).Select("company_id").Where("badge_template_id = ?", templateID))
+		if err := query().Where("id IN (?)", assigned).Find(&members).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(members) == 0 {
+		if err := query().Find(&members).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(members) == 0 {
+		members = append(members, SampleMember())
+	}
+	return members, nil
+}
+
+// SampleMember is a filled member used to preview templates when there are no members yet
+func SampleMember() model.Member {
+	gate := model.Gate{Name: "Главный вход", ShortName: "A"}
+	return model.Member{
+		Surname:     "Иванов",
+		Name:        "Иван",
+		Middlename:  "Иванович",
+		CompanyName: "Компания",
+		Document:    "0000 000000",
+		Barcode:     "0000000000000",
+		Birth:       time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		Accreditation: model.Accreditation{
+			Name:      "Аккредитация",
+			ShortName: "AKK",
+			Gates:     []model.Gate{gate},
+		},
+		Gates: []model.Gate{gate},
+	}
+}
+
+// saveTemplateVersion bumps the template version and stores the revision
+func saveTemplateVersion(tx *gorm.DB, template *model.BadgeTemplate, userID uuid.UUID, comment string) error {
+	var last uint
+	if err := tx.Model(&model.BadgeTemplateVersion{}).Where("template_id = ?", template.ID).
+		Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+	template.Version = last + 1
+	if err := tx.Model(&model.BadgeTemplate{}).Where("id = ?", template.ID).Update("version", template.Version).Error; err != nil {
+		return err
+	}
+	return tx.Create(&model.BadgeTemplateVersion{
+		TemplateID:   template.ID,
+		Version:      template.Version,
+		Name:         template.Name,
+		TemplateJSON: template.TemplateJSON,
+		UserID:       userID,
+		Comment:      comment,
+	}).Error
+}
@@ -0,0 +1,108 @@
+package badge
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func getBadgeTemplateVersions(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var versions []model.BadgeTemplateVersion
+	if err := db.Where("template_id = ?", id).Order("version DESC").Find(&versions).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, versions)
+}
+
+// rollbackBadgeTemplate restores the content of an old version as a new version
+func rollbackBadgeTemplate(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var input struct {
+		Version uint `json:"version"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	var template model.BadgeTemplate
+	if err := db.First(&template, id).Error; err != nil {
+		return c.String(http.StatusNotFound, "Шаблон не найден")
+	}
+	var version model.BadgeTemplateVersion
+	if err := db.Where("template_id = ? AND version = ?", id, input.Version).First(&version).Error; err != nil {
+		return c.String(http.StatusNotFound, "Версия шаблона не найдена")
+	}
+	if err := ValidateTemplate(db, template.ID, version.TemplateJSON); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if version.Name != template.Name {
+		// names are unique, deleted templates keep theirs
+		var taken int64
+		if err := db.Unscoped().Model(&model.BadgeTemplate{}).Where("name = ? AND id <> ?", version.Name, template.ID).Count(&taken).Error; err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		if taken > 0 {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Название версии «%s» уже занято другим шаблоном, переименуйте его перед откатом", version.Name))
+		}
+	}
+	userID, _ := utils.GetUser(c)
+	template.Name = version.Name
+	template.TemplateJSON = version.TemplateJSON
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&template).Error; err != nil {
+			return err
+		}
+		return saveTemplateVersion(tx, &template, userID, fmt.Sprintf("rollback to version %d", version.Version))
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, template)
+}
+
+// previewBadgeTemplate renders a saved template or an unsaved template_json for a member,
+// without member_id the sample member is used
+func previewBadgeTemplate(c echo.Context) error {
+	var input struct {
+		TemplateID   *uuid.UUID `json:"template_id"`
+		TemplateJSON string     `json:"template_json"`
+		MemberID     *uuid.UUID `json:"member_id"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	var member model.Member
+	if input.MemberID == nil {
+		member = SampleMember()
+	} else if err := PreloadTemplateData(db).First(&member, *input.MemberID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusNotFound, "Участник не найден")
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	templateJSON := input.TemplateJSON
+	if templateJSON == "" {
+		var template model.BadgeTemplate
+		var err error
+		if input.TemplateID != nil {
+			err = db.First(&template, *input.TemplateID).Error
+		} else {
+			template, err = NewTemplateResolver(db).ForMember(member)
+		}
+		if err != nil {
+			return c.String(http.StatusNotFound, "Шаблон не найден")
+		}
+		templateJSON = template.TemplateJSON
+	}
+	payload, err := ProcessBadgeTemplate(member, templateJSON)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Не удалось обработать шаблон бейджа: "+err.Error())
+	}
+	return c.JSONBlob(http.StatusOK, payload)
+}
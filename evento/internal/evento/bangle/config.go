@@ -0,0 +1,28 @@
+package bangle
+
+import (
+	"github.com/eugenetolok/evento/pkg/utils"
+	echojwt "github.com/labstack/echo-jwt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// InitBangles entry point of bangle types, issuing points and stock
+func InitBangles(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config) {
+	db = dbInstance
+	g.Use(echojwt.WithConfig(jwtConfig))
+	g.GET("/types", getTypes, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.POST("/types", createType, utils.RoleMiddleware([]string{"admin"}))
+	g.PUT("/types/:id", updateType, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.DELETE("/types/:id", deleteType, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/points", getPoints, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.POST("/points", createPoint, utils.RoleMiddleware([]string{"admin"}))
+	g.PUT("/points/:id", updatePoint, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.DELETE("/points/:id", deletePoint, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/stock", getStock, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.POST("/stock", addStock, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/issues", getIssues, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.GET("/report", getReport, utils.RoleMiddleware([]string{"admin", "operator"}))
+}
@@ -0,0 +1,130 @@
+package bangle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func getTypes(c echo.Context) error {
+	var types []model.BangleType
+	if err := db.Order("name").Find(&types).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, types)
+}
+
+func createType(c echo.Context) error {
+	var bangleType model.BangleType
+	if err := c.Bind(&bangleType); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	bangleType.ID = uuid.Nil
+	if err := validateType(bangleType); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err := db.Create(&bangleType).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, bangleType)
+}
+
+func updateType(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var bangleType model.BangleType
+	if err := db.First(&bangleType, id).Error; err != nil {
+		return c.String(http.StatusNotFound, "Тип браслета не найден")
+	}
+	if err := c.Bind(&bangleType); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	bangleType.ID = id
+	if err := validateType(bangleType); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err := db.Save(&bangleType).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, bangleType)
+}
+
+func deleteType(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	if err := db.Delete(&model.BangleType{}, id).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validateType(bangleType model.BangleType) error {
+	if bangleType.Name == "" {
+		return errors.New("Укажите название браслета")
+	}
+	var count int64
+	if bangleType.AccreditationID != nil {
+		db.Model(&model.Accreditation{}).Where("id = ?", *bangleType.AccreditationID).Count(&count)
+		if count == 0 {
+			return errors.New("Аккредитация не найдена")
+		}
+	}
+	if bangleType.EventID != nil {
+		db.Model(&model.Event{}).Where("id = ?", *bangleType.EventID).Count(&count)
+		if count == 0 {
+			return errors.New("Мероприятие не найдено")
+		}
+	}
+	return nil
+}
+
+func getPoints(c echo.Context) error {
+	var points []model.BanglePoint
+	if err := db.Order("name").Find(&points).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, points)
+}
+
+func createPoint(c echo.Context) error {
+	var point model.BanglePoint
+	if err := c.Bind(&point); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	point.ID = uuid.Nil
+	if point.Name == "" {
+		return c.String(http.StatusBadRequest, "Укажите название точки выдачи")
+	}
+	if err := db.Create(&point).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, point)
+}
+
+func updatePoint(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var point model.BanglePoint
+	if err := db.First(&point, id).Error; err != nil {
+		return c.String(http.StatusNotFound, "Точка выдачи не найдена")
+	}
+	if err := c.Bind(&point); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	point.ID = id
+	if point.Name == "" {
+		return c.String(http.StatusBadRequest, "Укажите название точки выдачи")
+	}
+	if err := db.Save(&point).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, point)
+}
+
+func deletePoint(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	if err := db.Delete(&model.BanglePoint{}, id).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
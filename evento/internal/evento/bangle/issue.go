@@ -0,0 +1,157 @@
+package bangle
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/keylock"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// IssueInput is the bangle given to a member, without a type the issue is only counted
+type IssueInput struct {
+	TypeID  *uuid.UUID `json:"type_id"`
+	PointID *uuid.UUID `json:"point_id"`
+	UID     string     `json:"uid"`
+}
+
+// issueLocks serializes issues of one point stock and one uid
+var issueLocks = keylock.New()
+
+// LockIssue blocks parallel issues taking the same point stock or binding the same uid,
+// the caller holds it until the transaction of Issue is committed
+func LockIssue(input IssueInput) (unlock func()) {
+	var keys []string
+	if input.PointID != nil && input.TypeID != nil {
+		keys = append(keys, "stock:"+input.PointID.String()+":"+input.TypeID.String())
+	}
+	if input.UID != "" {
+		keys = append(keys, "uid:"+input.UID)
+	}
+	return issueLocks.Lock(keys...)
+}
+
+// Issue records a bangle given to the member: the type must fit the member accreditation and events,
+// the point must have it in stock and the uid must not be bound to anyone or be a member barcode. The uid becomes a bangle credential
+// of the member, a previous bangle of the same type is replaced and its credentials are revoked and returned.
+// Callers hold LockIssue around the transaction. Validation errors are *echo.HTTPError
+func Issue(tx *gorm.DB, member model.Member, input IssueInput, userID uuid.UUID) (model.BangleIssue, []model.MemberCredential, error) {
+	issue := model.BangleIssue{MemberID: member.ID, TypeID: input.TypeID, PointID: input.PointID, UID: input.UID, UserID: userID, IssuedAt: time.Now()}
+	if input.TypeID != nil {
+		var bangleType model.BangleType
+		if err := tx.First(&bangleType, *input.TypeID).Error; err != nil {
+			return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "Тип браслета не найден")
+		}
+		if bangleType.AccreditationID != nil && *bangleType.AccreditationID != member.AccreditationID {
+			return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "Браслет не предназначен для аккредитации участника")
+		}
+		if bangleType.EventID != nil {
+			var count int64
+			tx.Table("member_events").Where("member_id = ? AND event_id = ?", member.ID, *bangleType.EventID).Count(&count)
+			if count == 0 {
+				return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "Участник не допущен на мероприятие браслета")
+			}
+		}
+	}
+	if input.PointID != nil {
+		if input.TypeID == nil {
+			return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "Укажите тип браслета")
+		}
+		var count int64
+		tx.Model(&model.BanglePoint{}).Where("id = ?", *input.PointID).Count(&count)
+		if count == 0 {
+			return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "Точка выдачи не найдена")
+		}
+		remaining, err := remainingStock(tx, *input.PointID, *input.TypeID)
+		if err != nil {
+			return issue, nil, err
+		}
+		if remaining <= 0 {
+			return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "На точке выдачи закончились браслеты этого типа")
+		}
+	}
+	if input.UID != "" {
+		taken, err := utils.CredentialTaken(tx, input.UID)
+		if err != nil {
+			return issue, nil, err
+		}
+		if taken {
+			return issue, nil, echo.NewHTTPError(http.StatusBadRequest, "Браслет с этим номером уже выдан")
+		}
+	}
+	previous := func() *gorm.DB {
+		query := tx.Model(&model.BangleIssue{}).Where("member_id = ? AND replaced_at IS NULL", member.ID)
+		if input.TypeID != nil {
+			return query.Where("type_id = ?", *input.TypeID)
+		}
+		return query.Where("type_id IS NULL")
+	}
+	var replacedUIDs []string
+	if err := previous().Where("uid <> ''").Pluck("uid", &replacedUIDs).Error; err != nil {
+		return issue, nil, err
+	}
+	if err := previous().Update("replaced_at", issue.IssuedAt).Error; err != nil {
+		return issue, nil, err
+	}
+	var revoked []model.MemberCredential
+	if len(replacedUIDs) > 0 {
+		query := tx.Where("member_id = ? AND type = ? AND value IN ? AND status <> ?", member.ID, model.CredentialBangle, replacedUIDs, model.CredentialRevoked)
+		if err := query.Find(&revoked).Error; err != nil {
+			return issue, nil, err
+		}
+		for i := range revoked {
+			revoked[i].Status = model.CredentialRevoked
+			revoked[i].UnboundAt = &issue.IssuedAt
+			if err := tx.Save(&revoked[i]).Error; err != nil {
+				return issue, nil, err
+			}
+		}
+	}
+	if input.UID != "" {
+		bound := model.MemberCredential{MemberID: member.ID, Type: model.CredentialBangle, Value: input.UID, Status: model.CredentialActive, BoundAt: issue.IssuedAt}
+		if err := tx.Create(&bound).Error; err != nil {
+			return issue, nil, err
+		}
+	}
+	return issue, revoked, tx.Create(&issue).Error
+}
+
+// remainingStock is the delivered quantity of the type at the point minus the issued bangles
+func remainingStock(tx *gorm.DB, pointID, typeID uuid.UUID) (int64, error) {
+	var received struct{ Total int64 }
+	if err := tx.Model(&model.BangleStock{}).Select("COALESCE(SUM(quantity), 0) AS total").
+		Where("point_id = ? AND type_id = ?", pointID, typeID).Scan(&received).Error; err != nil {
+		return 0, err
+	}
+	var issued int64
+	if err := tx.Model(&model.BangleIssue{}).Where("point_id = ? AND type_id = ?", pointID, typeID).Count(&issued).Error; err != nil {
+		return 0, err
+	}
+	return received.Total - issued, nil
+}
+
+func getIssues(c echo.Context) error {
+	query := db.Order("issued_at DESC")
+	for _, filter := range []string{"member_id", "point_id", "type_id"} {
+		if raw := c.QueryParam(filter); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return c.String(http.StatusBadRequest, "Неверный "+filter)
+			}
+			query = query.Where(filter+" = ?", id)
+		}
+	}
+	if uid := c.QueryParam("uid"); uid != "" {
+		query = query.Where("uid = ?", uid)
+	}
+	var issues []model.BangleIssue
+	if err := query.Find(&issues).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, issues)
+}
@@ -0,0 +1,99 @@
+package bangle
+
+import (
+	"net/http"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ReportRow is the stock of a bangle type at an issuing point
+type ReportRow struct {
+	PointID   uuid.UUID `json:"point_id"`
+	Point     string    `json:"point"`
+	TypeID    uuid.UUID `json:"type_id"`
+	Type      string    `json:"type"`
+	Received  int64     `json:"received"`
+	Issued    int64     `json:"issued"`
+	Remaining int64     `json:"remaining"`
+}
+
+func getStock(c echo.Context) error {
+	query := db.Order("created_at DESC")
+	for _, filter := range []string{"point_id", "type_id"} {
+		if raw := c.QueryParam(filter); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return c.String(http.StatusBadRequest, "Неверный "+filter)
+			}
+			query = query.Where(filter+" = ?", id)
+		}
+	}
+	var stock []model.BangleStock
+	if err := query.Find(&stock).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, stock)
+}
+
+// addStock records a delivery to an issuing point, a negative quantity writes bangles off
+func addStock(c echo.Context) error {
+	var stock model.BangleStock
+	if err := c.Bind(&stock); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if stock.Quantity == 0 {
+		return c.String(http.StatusBadRequest, "Укажите количество")
+	}
+	var count int64
+	db.Model(&model.BanglePoint{}).Where("id = ?", stock.PointID).Count(&count)
+	if count == 0 {
+		return c.String(http.StatusBadRequest, "Точка выдачи не найдена")
+	}
+	db.Model(&model.BangleType{}).Where("id = ?", stock.TypeID).Count(&count)
+	if count == 0 {
+		return c.String(http.StatusBadRequest, "Тип браслета не найден")
+	}
+	if stock.Quantity < 0 {
+		remaining, err := remainingStock(db, stock.PointID, stock.TypeID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		if remaining+int64(stock.Quantity) < 0 {
+			return c.String(http.StatusBadRequest, "Нельзя списать больше, чем осталось на точке")
+		}
+	}
+	stock.ID = uuid.Nil
+	stock.UserID, _ = utils.GetUser(c)
+	if err := db.Create(&stock).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, stock)
+}
+
+// getReport returns received, issued and remaining bangles per issuing point and type
+func getReport(c echo.Context) error {
+	var rows []ReportRow
+	err := db.Raw(`SELECT p.id AS point_id, p.name AS point, t.id AS type_id, t.name AS type,
+			COALESCE((SELECT SUM(s.quantity) FROM bangle_stocks s WHERE s.point_id = p.id AND s.type_id = t.id AND s.deleted_at IS NULL), 0) AS received,
+			(SELECT COUNT(*) FROM bangle_issues i WHERE i.point_id = p.id AND i.type_id = t.id AND i.deleted_at IS NULL) AS issued
+		FROM bangle_points p CROSS JOIN bangle_types t
+		WHERE p.deleted_at IS NULL AND t.deleted_at IS NULL
+		ORDER BY p.name, t.name`).Scan(&rows).Error
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	report := make([]ReportRow, 0, len(rows))
+	for _, row := range rows {
+		if row.Received == 0 && row.Issued == 0 {
+			continue
+		}
+		row.Remaining = row.Received - row.Issued
+		report = append(report, row)
+	}
+	var unassigned int64
+	db.Model(&model.BangleIssue{}).Where("point_id IS NULL").Count(&unassigned)
+	return c.JSON(http.StatusOK, map[string]interface{}{"rows": report, "issued_without_point": unassigned})
+}
@@ -0,0 +1,77 @@
+package company
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func getCompanyBadgeTemplates(c echo.Context) error {
+	company, err := findDeadlineCompany(c, false)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	var templates []model.CompanyBadgeTemplate
+	if err := db.Where("company_id = ?", company.ID).Find(&templates).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, templates)
+}
+
+// setCompanyBadgeTemplates replaces badge templates of the company, an empty accreditation_id applies to all accreditations
+func setCompanyBadgeTemplates(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	company, err := findDeadlineCompany(c, true)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	var templates []model.CompanyBadgeTemplate
+	if err := c.Bind(&templates); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	seen := map[uuid.UUID]bool{}
+	for i := range templates {
+		template := &templates[i]
+		if seen[template.AccreditationID] {
+			return c.String(http.StatusBadRequest, "Повторяющийся шаблон для аккредитации")
+		}
+		seen[template.AccreditationID] = true
+		var count int64
+		db.Model(&model.BadgeTemplate{}).Where("id = ?", template.BadgeTemplateID).Count(&count)
+		if count == 0 {
+			return c.String(http.StatusBadRequest, "Шаблон бейджа не найден")
+		}
+		if template.AccreditationID != uuid.Nil {
+			db.Model(&model.Accreditation{}).Where("id = ?", template.AccreditationID).Count(&count)
+			if count == 0 {
+				return c.String(http.StatusBadRequest, "Аккредитация не найдена")
+			}
+		}
+		template.ID = uuid.Nil
+		template.CompanyID = company.ID
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("company_id = ?", company.ID).Delete(&model.CompanyBadgeTemplate{}).Error; err != nil {
+			return err
+		}
+		if len(templates) > 0 {
+			if err := tx.Create(&templates).Error; err != nil {
+				return err
+			}
+		}
+		details, _ := json.Marshal(templates)
+		return logCompanyHistory(tx, c, company.ID, "badge_templates", string(details))
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, templates)
+}
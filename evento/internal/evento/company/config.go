@@ -12,7 +12,6 @@ var db *gorm.DB
 // InitCompanies entry point of companys
 func InitCompanies(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config) {
 	db = dbInstance
-	startCompanyFreezeScheduler(db)
 	g.Use(echojwt.WithConfig(jwtConfig))
 	// TODO: write restrict logic inside functions for all kind of roles (not middleware)
 	g.GET("/search", searchCompanies, utils.RoleMiddleware([]string{"admin", "operator"}))
@@ -21,6 +20,7 @@ func InitCompanies(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config)
 	g.GET("/freeze/status", getCompanyFreezeStatus, utils.RoleMiddleware([]string{"admin"}))
 	g.POST("/freeze/schedule", scheduleCompanyFreezeAll, utils.RoleMiddleware([]string{"admin"}))
 	g.POST("/freeze/all", setCompanyFreezeAllNow, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/freeze/exceptions", getCompanyFreezeExceptions, utils.RoleMiddleware([]string{"admin"}))
 	g.POST("", createCompany, utils.RoleMiddleware([]string{"admin", "editor"}))
 	g.GET("/:id", getCompany, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor"}))
 	g.PUT("/:id", updateCompany, utils.RoleMiddleware([]string{"admin", "editor"}), utils.UUIDMiddleware)
@@ -30,6 +30,18 @@ func InitCompanies(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config)
 	g.POST("/import", importTemplate, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.GET("/limits", getCompanyLimits, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.POST("/:id/freeze", freezeCompany, utils.RoleMiddleware([]string{"admin", "editor"}))
+	g.POST("/:id/freeze/schedule", scheduleCompanyFreeze, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor"}))
+	g.DELETE("/:id/freeze/schedule", cancelCompanyFreezeSchedule, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor"}))
+	g.PUT("/:id/freeze/exception", setCompanyFreezeException, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/limit-requests", getLimitRequests, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
+	g.POST("/limit-requests", createLimitRequest, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
+	g.GET("/limit-requests/:id", getLimitRequest, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
+	g.POST("/limit-requests/:id/decision", decideLimitRequest, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor"}))
+	g.POST("/limit-requests/:id/cancel", cancelLimitRequest, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
+	g.GET("/:id/deadline-overrides", getCompanyDeadlineOverrides, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor"}))
+	g.PUT("/:id/deadline-overrides", setCompanyDeadlineOverrides, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/:id/badge-templates", getCompanyBadgeTemplates, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor"}))
+	g.PUT("/:id/badge-templates", setCompanyBadgeTemplates, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("/:id/printlimit", printLimit, utils.RoleMiddleware([]string{"admin", "operator"}))
 	// gates
 	g.POST("/:id/add-gate-to-members", addGateToAllMembers, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
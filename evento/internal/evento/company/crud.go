@@ -49,6 +49,9 @@ func createCompany(c echo.Context) error {
 	if companyIn.InEventMembersLimit > companyIn.MembersLimit {
 		return c.String(http.StatusBadRequest, `Единовременный лимит участников выше максимального`)
 	}
+	if err := checkCompanyCapacity(db, uuid.Nil, companyIn); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 	userID, _ := utils.GetUser(c)
 	copier.Copy(&company, &companyIn)
 	company.EditorID = userID
@@ -223,6 +226,18 @@ func updateCompany(c echo.Context) error {
 	if companyIn.InEventMembersLimit > companyIn.MembersLimit {
 		return c.String(http.StatusBadRequest, `Единовременный лимит участников выше максимального`)
 	}
+	if _, role := utils.GetUser(c); company.LimitsLocked && role != "admin" {
+		changed, err := limitsChangeRequested(db, company, companyIn)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		if changed {
+			return c.String(http.StatusForbidden, `Изменение лимитов компании заблокировано`)
+		}
+	}
+	if err := checkCompanyCapacity(db, company.ID, companyIn); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 
 	copier.CopyWithOption(&company, &companyIn, copier.Option{IgnoreEmpty: true})
 
@@ -0,0 +1,94 @@
+package company
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func getCompanyDeadlineOverrides(c echo.Context) error {
+	company, err := findDeadlineCompany(c, false)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	var overrides []model.CompanyDeadlineOverride
+	if err := db.Where("company_id = ?", company.ID).Find(&overrides).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, overrides)
+}
+
+// setCompanyDeadlineOverrides replaces registration deadline overrides of the company
+func setCompanyDeadlineOverrides(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	company, err := findDeadlineCompany(c, true)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	var overrides []model.CompanyDeadlineOverride
+	if err := c.Bind(&overrides); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	seen := map[uuid.UUID]bool{}
+	for i := range overrides {
+		override := &overrides[i]
+		if (override.AccreditationID == uuid.Nil) == (override.EventID == uuid.Nil) {
+			return c.String(http.StatusBadRequest, "Укажите либо аккредитацию, либо мероприятие")
+		}
+		targetID := override.AccreditationID
+		if override.EventID != uuid.Nil {
+			targetID = override.EventID
+		}
+		if seen[targetID] {
+			return c.String(http.StatusBadRequest, "Повторяющееся исключение")
+		}
+		seen[targetID] = true
+		override.ID = uuid.Nil
+		override.CompanyID = company.ID
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("company_id = ?", company.ID).Delete(&model.CompanyDeadlineOverride{}).Error; err != nil {
+			return err
+		}
+		if len(overrides) == 0 {
+			return nil
+		}
+		if err := tx.Create(&overrides).Error; err != nil {
+			return err
+		}
+		details, _ := json.Marshal(overrides)
+		return logCompanyHistory(tx, c, company.ID, "deadline_overrides", string(details))
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, overrides)
+}
+
+// findDeadlineCompany loads the company of :id and checks access
+func findDeadlineCompany(c echo.Context, editRequest bool) (model.Company, error) {
+	var company model.Company
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return company, echo.NewHTTPError(http.StatusBadRequest, `{"error":"invalid id"}`)
+	}
+	if err := db.Preload("User").First(&company, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return company, echo.NewHTTPError(http.StatusNotFound, `Компания не найдена`)
+		}
+		return company, err
+	}
+	if !checkRole(c, company, editRequest) {
+		return company, echo.NewHTTPError(http.StatusForbidden, `У пользователя недостаточно привелегий`)
+	}
+	return company, nil
+}
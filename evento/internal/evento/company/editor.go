@@ -1,7 +1,6 @@
 package company
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/eugenetolok/evento/pkg/model"
@@ -11,7 +10,6 @@ import (
 
 func editorCompanies(c echo.Context) error {
 	userID, _ := utils.GetUser(c)
-	fmt.Println("$$$$$$$$$$$$$$$", userID)
 	query := `
 	SELECT
 		c.id,
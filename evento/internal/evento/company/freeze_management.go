@@ -1,29 +1,52 @@
 package company
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/eugenetolok/evento/pkg/jobs"
+	"github.com/eugenetolok/evento/pkg/metrics"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 )
 
 const companyFreezeSchedulerInterval = 30 * time.Second
 
-var companyFreezeSchedulerOnce sync.Once
+// companyFreezeTarget narrows a mass freeze to an editor group or a list of companies, empty means all companies
+type companyFreezeTarget struct {
+	EditorID   *uuid.UUID  `json:"editor_id,omitempty"`
+	CompanyIDs []uuid.UUID `json:"company_ids,omitempty"`
+}
 
 type companyFreezeScheduleRequest struct {
+	companyFreezeTarget
 	Action    string `json:"action"`
 	ExecuteAt string `json:"execute_at"`
+	Reason    string `json:"reason"`
 }
 
 type companyFreezeAllRequest struct {
+	companyFreezeTarget
 	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+type companyFreezeExceptionRequest struct {
+	Exempt bool `json:"exempt"`
+}
+
+type companyFreezeExceptionResponse struct {
+	CompanyID   uuid.UUID `json:"company_id"`
+	CompanyName string    `json:"company_name"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Frozen      bool      `json:"frozen"`
 }
 
 type companyFreezeStatusResponse struct {
@@ -32,6 +55,7 @@ type companyFreezeStatusResponse struct {
 	ScheduledCompanies int64      `json:"scheduled_companies"`
 	NextAction         string     `json:"next_action,omitempty"`
 	NextScheduledAt    *time.Time `json:"next_scheduled_at,omitempty"`
+	ExemptCompanies    int64      `json:"exempt_companies"`
 }
 
 type companyFreezeActionResponse struct {
@@ -45,23 +69,17 @@ type companyFreezeNextScheduleRow struct {
 	FrozenAt     *time.Time
 }
 
-func startCompanyFreezeScheduler(database *gorm.DB) {
-	companyFreezeSchedulerOnce.Do(func() {
-		go func() {
-			if err := applyScheduledCompanyFreeze(database, time.Now()); err != nil {
-				fmt.Println("company freeze scheduler startup apply error:", err)
-			}
-
-			ticker := time.NewTicker(companyFreezeSchedulerInterval)
-			defer ticker.Stop()
-
-			for range ticker.C {
-				if err := applyScheduledCompanyFreeze(database, time.Now()); err != nil {
-					fmt.Println("company freeze scheduler tick apply error:", err)
-				}
-			}
-		}()
-	})
+// FreezeSchedulerJob applies scheduled company freeze/unfreeze actions
+func FreezeSchedulerJob(database *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "company_freeze_scheduler",
+		Description: "Применяет запланированную заморозку и разморозку компаний",
+		Interval:    companyFreezeSchedulerInterval,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			return runCompanyFreezeScheduler(database.WithContext(ctx))
+		},
+	}
 }
 
 func getCompanyFreezeStatus(c echo.Context) error {
@@ -90,7 +108,13 @@ func getCompanyFreezeStatus(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
+	var exempt int64
+	if err := db.Model(&model.User{}).Where("role = ? AND freeze_exempt = ?", "company", true).Count(&exempt).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
 	response := companyFreezeStatusResponse{
+		ExemptCompanies:    exempt,
 		CompaniesTotal:     total,
 		FrozenCompanies:    frozen,
 		ScheduledCompanies: scheduled,
@@ -124,12 +148,14 @@ func scheduleCompanyFreezeAll(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Дата и время должны быть в будущем")
 	}
 
-	result := db.Model(&model.User{}).
-		Where("role = ?", "company").
-		Updates(map[string]interface{}{
-			"frozen_action": action,
-			"frozen_at":     executeAt,
-		})
+	updates := map[string]interface{}{
+		"frozen_action": action,
+		"frozen_at":     executeAt,
+	}
+	if action == "freeze" {
+		updates["frozen_reason"] = strings.TrimSpace(request.Reason)
+	}
+	result := companyFreezeUsers(db, request.companyFreezeTarget, action).Updates(updates)
 	if result.Error != nil {
 		return c.String(http.StatusInternalServerError, result.Error.Error())
 	}
@@ -157,14 +183,8 @@ func setCompanyFreezeAllNow(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
-	freeze := action == "freeze"
-	result := db.Model(&model.User{}).
-		Where("role = ?", "company").
-		Updates(map[string]interface{}{
-			"frozen":        freeze,
-			"frozen_action": "",
-			"frozen_at":     nil,
-		})
+	result := companyFreezeUsers(db, request.companyFreezeTarget, action).
+		Updates(freezeUpdates(action == "freeze", request.Reason))
 	if result.Error != nil {
 		return c.String(http.StatusInternalServerError, result.Error.Error())
 	}
@@ -216,8 +236,16 @@ func parseFreezeDateTime(rawDateTime string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("неверный формат даты и времени")
 }
 
-func applyScheduledCompanyFreeze(database *gorm.DB, now time.Time) error {
-	return database.Transaction(func(tx *gorm.DB) error {
+func runCompanyFreezeScheduler(database *gorm.DB) error {
+	applied, err := applyScheduledCompanyFreeze(database, time.Now())
+	metrics.ObserveFreezeSchedulerRun(applied, err)
+	return err
+}
+
+// applyScheduledCompanyFreeze applies due scheduled actions and returns counts per action
+func applyScheduledCompanyFreeze(database *gorm.DB, now time.Time) (map[string]int, error) {
+	applied := map[string]int{}
+	err := database.Transaction(func(tx *gorm.DB) error {
 		var dueUsers []model.User
 		if err := tx.
 			Where("role = ? AND frozen_at IS NOT NULL AND frozen_action IN ? AND frozen_at <= ?", "company", []string{"freeze", "unfreeze"}, now).
@@ -227,17 +255,184 @@ func applyScheduledCompanyFreeze(database *gorm.DB, now time.Time) error {
 
 		for _, user := range dueUsers {
 			freeze := user.FrozenAction == "freeze"
-			if err := tx.Model(&model.User{}).
-				Where("id = ?", user.ID).
-				Updates(map[string]interface{}{
-					"frozen":        freeze,
-					"frozen_action": "",
-					"frozen_at":     nil,
-				}).Error; err != nil {
+			updates := freezeUpdates(freeze, user.FrozenReason)
+			if freeze && user.FreezeExempt {
+				// exempt companies keep editing, the scheduled freeze is dropped
+				updates = map[string]interface{}{"frozen_action": "", "frozen_at": nil}
+				applied["exempt"]++
+			} else {
+				applied[user.FrozenAction]++
+			}
+			if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
 				return err
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// companyFreezeUsers selects company users of the target, exempt ones are left out of freeze
+func companyFreezeUsers(database *gorm.DB, target companyFreezeTarget, action string) *gorm.DB {
+	query := database.Model(&model.User{}).Where("role = ?", "company")
+	if target.EditorID != nil {
+		query = query.Where("company_id IN (?)", database.Model(&model.Company{}).Select("id").Where("editor_id = ?", *target.EditorID))
+	}
+	if len(target.CompanyIDs) > 0 {
+		query = query.Where("company_id IN ?", target.CompanyIDs)
+	}
+	if action == "freeze" {
+		query = query.Where("freeze_exempt = ?", false)
+	}
+	return query
+}
+
+// freezeUpdates are the user columns set when a freeze or unfreeze is applied
+func freezeUpdates(freeze bool, reason string) map[string]interface{} {
+	updates := map[string]interface{}{
+		"frozen":        freeze,
+		"frozen_action": "",
+		"frozen_at":     nil,
+		"frozen_reason": "",
+	}
+	if freeze {
+		updates["frozen_reason"] = strings.TrimSpace(reason)
+	}
+	return updates
+}
+
+// scheduleCompanyFreeze schedules freeze or unfreeze of one company
+func scheduleCompanyFreeze(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	company, err := findFreezeCompany(c)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+
+	var request companyFreezeScheduleRequest
+	if err := c.Bind(&request); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	action, err := normalizeFreezeAction(request.Action)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	executeAt, err := parseFreezeDateTime(request.ExecuteAt)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if !executeAt.After(time.Now()) {
+		return c.String(http.StatusBadRequest, "Дата и время должны быть в будущем")
+	}
+	if action == "freeze" && company.User.FreezeExempt {
+		return c.String(http.StatusBadRequest, "Компания в списке исключений заморозки")
+	}
+
+	updates := map[string]interface{}{
+		"frozen_action": action,
+		"frozen_at":     executeAt,
+	}
+	if action == "freeze" {
+		updates["frozen_reason"] = strings.TrimSpace(request.Reason)
+	}
+	if err := db.Model(&model.User{}).Where("id = ?", company.User.ID).Updates(updates).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	logCompanyHistory(db, c, company.ID, "freeze_schedule", fmt.Sprintf(`{"action":%q,"execute_at":%q,"reason":%q}`, action, executeAt.Format(time.RFC3339), request.Reason))
+	return c.JSON(http.StatusOK, companyFreezeActionResponse{Action: action, AffectedCount: 1, ExecuteAt: &executeAt})
+}
+
+// cancelCompanyFreezeSchedule drops a pending scheduled action of one company
+func cancelCompanyFreezeSchedule(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	company, err := findFreezeCompany(c)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	updates := map[string]interface{}{"frozen_action": "", "frozen_at": nil}
+	if !company.User.Frozen {
+		updates["frozen_reason"] = ""
+	}
+	if err := db.Model(&model.User{}).Where("id = ?", company.User.ID).Updates(updates).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	logCompanyHistory(db, c, company.ID, "freeze_schedule_cancel", "{}")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func getCompanyFreezeExceptions(c echo.Context) error {
+	var exceptions []companyFreezeExceptionResponse
+	if err := db.Model(&model.User{}).
+		Select("companies.id AS company_id, companies.name AS company_name, users.id AS user_id, users.username, users.frozen").
+		Joins("JOIN companies ON companies.id = users.company_id AND companies.deleted_at IS NULL").
+		Where("users.role = ? AND users.freeze_exempt = ?", "company", true).
+		Order("companies.name").
+		Scan(&exceptions).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, exceptions)
+}
+
+// setCompanyFreezeException adds or removes a company from the freeze exception list,
+// adding also unfreezes the company and drops its scheduled freeze
+func setCompanyFreezeException(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	company, err := findFreezeCompany(c)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	var request companyFreezeExceptionRequest
+	if err := c.Bind(&request); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	updates := map[string]interface{}{"freeze_exempt": request.Exempt}
+	if request.Exempt {
+		updates["frozen"] = false
+		updates["frozen_reason"] = ""
+		if company.User.FrozenAction == "freeze" {
+			updates["frozen_action"] = ""
+			updates["frozen_at"] = nil
+		}
+	}
+	if err := db.Model(&model.User{}).Where("id = ?", company.User.ID).Updates(updates).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	logCompanyHistory(db, c, company.ID, "freeze_exception", fmt.Sprintf(`{"exempt":%t}`, request.Exempt))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findFreezeCompany loads the company of :id with its user and checks edit rights
+func findFreezeCompany(c echo.Context) (model.Company, error) {
+	var company model.Company
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return company, echo.NewHTTPError(http.StatusBadRequest, `{"error":"invalid id"}`)
+	}
+	if err := db.Preload("User").First(&company, id).Error; err != nil {
+		return company, echo.NewHTTPError(http.StatusNotFound, `Компания не найдена`)
+	}
+	if !checkRole(c, company, true) {
+		return company, echo.NewHTTPError(http.StatusForbidden, `У пользователя недостаточно привелегий`)
+	}
+	if company.User.ID == uuid.Nil {
+		return company, echo.NewHTTPError(http.StatusNotFound, `{"error":"user is not found"}`)
+	}
+	return company, nil
+}
+
+func companyHTTPError(c echo.Context, err error) error {
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+	}
+	return c.String(http.StatusInternalServerError, err.Error())
 }
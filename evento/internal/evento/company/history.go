@@ -1,6 +1,7 @@
 package company
 
 import (
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
@@ -11,11 +12,17 @@ import (
 // logCompanyHistory creates and saves an CompanyHistory record within a transaction.
 func logCompanyHistory(tx *gorm.DB, c echo.Context, companyID uuid.UUID, changeType string, details string) error {
 	userID, _ := utils.GetUser(c)
+	return logCompanyHistoryAs(tx, userID, logger.EchoRequestID(c), companyID, changeType, details)
+}
+
+// logCompanyHistoryAs records a history entry for the given user outside of a request
+func logCompanyHistoryAs(tx *gorm.DB, userID uuid.UUID, requestID string, companyID uuid.UUID, changeType string, details string) error {
 	history := model.CompanyHistory{
 		CompanyID:  companyID,
 		UserID:     userID,
 		ChangeType: changeType,
 		Details:    details,
+		RequestID:  requestID,
 	}
 	return tx.Create(&history).Error
 }
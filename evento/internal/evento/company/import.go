@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
@@ -191,22 +192,47 @@ func importTemplate(c echo.Context) error {
 	}
 	defer src.Close()
 
-	var accreditations []model.Accreditation
-	if err := db.Order("position desc").Find(&accreditations).Error; err != nil {
+	xlsxFile, err := xlsx.OpenReaderAt(src, file.Size)
+	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
-	var events []model.Event
-	if err := db.Order("position desc").Find(&events).Error; err != nil {
+	if _, err := ImportCompaniesXLSX(db, xlsxFile, userID, logger.EchoRequestID(c)); err != nil {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+
+	return c.String(http.StatusOK, "Компании успешно импортированы")
+}
+
+// ImportedCompany holds the generated credentials of an imported company user
+type ImportedCompany struct {
+	CompanyID uuid.UUID
+	Name      string
+	INN       string
+	Username  string
+	Password  string
+}
+
+// ImportCompaniesXLSX creates companies, their limits and users from the import template.
+// requestID is stored in company history. Validation errors are returned as *echo.HTTPError with a user-facing message.
+func ImportCompaniesXLSX(database *gorm.DB, xlsxFile *xlsx.File, userID uuid.UUID, requestID string) ([]ImportedCompany, error) {
+	var accreditations []model.Accreditation
+	if err := database.Order("position desc").Find(&accreditations).Error; err != nil {
+		return nil, err
+	}
+	var events []model.Event
+	if err := database.Order("position desc").Find(&events).Error; err != nil {
+		return nil, err
+	}
 	var gates []model.Gate
-	if err := db.Order("position desc").Where("additional = ?", true).Find(&gates).Error; err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Order("position desc").Where("additional = ?", true).Find(&gates).Error; err != nil {
+		return nil, err
 	}
 
-	xlsxFile, err := xlsx.OpenReaderAt(src, file.Size)
-	if err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+	if len(xlsxFile.Sheets) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Файл не содержит листов")
 	}
 	sheet := xlsxFile.Sheets[0]
 
@@ -225,12 +251,13 @@ func importTemplate(c echo.Context) error {
 
 	headerRow, err := sheet.Row(1) // Header row is the second row (index 1)
 	if err != nil {
-		return c.String(http.StatusInternalServerError, "Нет заголовков таблиц")
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Нет заголовков таблиц")
 	}
 
 	var importErrors []string
 	var companiesToCreate []model.Company
 	var usersToCreate []model.User
+	var imported []ImportedCompany
 	var companyLimitsToCreate struct {
 		accreditationLimits []model.CompanyAccreditationLimit
 		eventLimits         []model.CompanyEventLimit
@@ -329,11 +356,11 @@ func importTemplate(c echo.Context) error {
 	}
 
 	if len(importErrors) > 0 {
-		return c.String(http.StatusBadRequest, strings.Join(importErrors, "\n"))
+		return nil, echo.NewHTTPError(http.StatusBadRequest, strings.Join(importErrors, "\n"))
 	}
 
 	// Perform database operations in a transaction
-	err = db.Transaction(func(tx *gorm.DB) error {
+	err = database.Transaction(func(tx *gorm.DB) error {
 		for i, company := range companiesToCreate {
 			if err := tx.Create(&company).Error; err != nil {
 				if strings.Contains(err.Error(), "UNIQUE constraint failed: companies.inn") {
@@ -367,6 +394,7 @@ func importTemplate(c echo.Context) error {
 
 			// Create user for this company
 			user := usersToCreate[i] // Assuming usersToCreate is in same order as companiesToCreate
+			imported = append(imported, ImportedCompany{CompanyID: company.ID, Name: company.Name, INN: company.INN, Username: user.Username, Password: user.Password})
 			hashedPassword, hashErr := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 			if hashErr != nil {
 				return fmt.Errorf("Строка %d: Ошибка хеширования пароля для пользователя компании %s: %s", i+4, company.Name, hashErr.Error())
@@ -377,16 +405,16 @@ func importTemplate(c echo.Context) error {
 			}
 			tx.Preload("User").Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").Preload("Members.Accreditation").Preload("Autos").First(&company, company.ID)
 			companyDetails, _ := json.Marshal(company)
-			logCompanyHistory(tx, c, company.ID, "create", string(companyDetails))
+			logCompanyHistoryAs(tx, userID, requestID, company.ID, "create", string(companyDetails))
 		}
 		return nil
 	})
 
 	if err != nil {
-		return c.String(http.StatusBadRequest, err.Error()) // Return detailed error from transaction
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error()) // Return detailed error from transaction
 	}
 
-	return c.String(http.StatusOK, "Компании успешно импортированы")
+	return imported, nil
 }
 
 func parseUint(value string) uint {
@@ -0,0 +1,386 @@
+package company
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Statuses of a limit change request
+const (
+	limitRequestPending           = "pending"
+	limitRequestApproved          = "approved"
+	limitRequestPartiallyApproved = "partially_approved"
+	limitRequestRejected          = "rejected"
+	limitRequestCancelled         = "cancelled"
+)
+
+type limitRequestIn struct {
+	Justification string `json:"justification"`
+	Items         []struct {
+		Kind           string    `json:"kind"`
+		TargetID       uuid.UUID `json:"target_id"`
+		RequestedLimit uint      `json:"requested_limit"`
+	} `json:"items"`
+}
+
+type limitRequestDecisionIn struct {
+	Decision string `json:"decision"`
+	Comment  string `json:"comment"`
+	// Items sets approved limits by item id, items not listed get the requested limit on approve
+	Items []struct {
+		ID            uuid.UUID `json:"id"`
+		ApprovedLimit uint      `json:"approved_limit"`
+	} `json:"items"`
+}
+
+func getLimitRequests(c echo.Context) error {
+	userID, role := utils.GetUser(c)
+	query := db.Preload("Items").Order("submitted_at DESC")
+	switch role {
+	case "company":
+		query = query.Where("company_id IN (?)", db.Model(&model.User{}).Select("company_id").Where("id = ?", userID))
+	case "editor":
+		query = query.Where("company_id IN (?)", db.Model(&model.Company{}).Select("id").Where("editor_id = ?", userID))
+	}
+	if companyID := c.QueryParam("company_id"); companyID != "" {
+		query = query.Where("company_id = ?", companyID)
+	}
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var requests []model.LimitChangeRequest
+	if err := query.Find(&requests).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, requests)
+}
+
+func getLimitRequest(c echo.Context) error {
+	request, _, err := findLimitRequest(c, false)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// createLimitRequest submits a limit increase request of the user's company, admins and editors pass company_id
+func createLimitRequest(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	companyID, err := utils.ResolveCompanyIDForManage(c, db, c.QueryParam("company_id"))
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	var company model.Company
+	if err := db.Select("id", "limits_locked").First(&company, companyID).Error; err != nil {
+		return c.String(http.StatusNotFound, `Компания не найдена`)
+	}
+	if company.LimitsLocked {
+		return c.String(http.StatusForbidden, `Изменение лимитов компании заблокировано`)
+	}
+	var input limitRequestIn
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	input.Justification = strings.TrimSpace(input.Justification)
+	if input.Justification == "" {
+		return c.String(http.StatusBadRequest, "Укажите обоснование заявки")
+	}
+	if len(input.Items) == 0 {
+		return c.String(http.StatusBadRequest, "Заявка не содержит лимитов")
+	}
+
+	var pending int64
+	if err := db.Model(&model.LimitChangeRequest{}).Where("company_id = ? AND status = ?", companyID, limitRequestPending).Count(&pending).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if pending > 0 {
+		return c.String(http.StatusConflict, "У компании уже есть заявка на рассмотрении")
+	}
+
+	userID, _ := utils.GetUser(c)
+	request := model.LimitChangeRequest{
+		CompanyID:     companyID,
+		RequestedByID: userID,
+		Status:        limitRequestPending,
+		Justification: input.Justification,
+		SubmittedAt:   time.Now(),
+	}
+	seen := map[string]bool{}
+	for _, item := range input.Items {
+		key := item.Kind + ":" + item.TargetID.String()
+		if seen[key] {
+			return c.String(http.StatusBadRequest, "Лимит указан в заявке несколько раз")
+		}
+		seen[key] = true
+		current, err := currentLimit(db, companyID, item.Kind, item.TargetID)
+		if err != nil {
+			return companyHTTPError(c, err)
+		}
+		if item.RequestedLimit <= current {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Запрошенный лимит должен быть больше текущего (%d)", current))
+		}
+		request.Items = append(request.Items, model.LimitChangeRequestItem{
+			Kind:           item.Kind,
+			TargetID:       item.TargetID,
+			CurrentLimit:   current,
+			RequestedLimit: item.RequestedLimit,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&request).Error; err != nil {
+			// a parallel submit won, one pending request per company is enforced by a unique index
+			if strings.Contains(err.Error(), "UNIQUE") {
+				return echo.NewHTTPError(http.StatusConflict, "У компании уже есть заявка на рассмотрении")
+			}
+			return err
+		}
+		details, _ := json.Marshal(request)
+		return logCompanyHistory(tx, c, companyID, "limit_request", string(details))
+	})
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// decideLimitRequest approves, partially approves or rejects a pending request,
+// approved limits are applied to the company limit rows in the same transaction
+func decideLimitRequest(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	request, company, err := findLimitRequest(c, true)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	if request.Status != limitRequestPending {
+		return c.String(http.StatusConflict, "Заявка уже рассмотрена")
+	}
+	var input limitRequestDecisionIn
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	approved := map[uuid.UUID]uint{}
+	for _, item := range input.Items {
+		approved[item.ID] = item.ApprovedLimit
+	}
+	// locked limits are changed only by admins, as in updateCompany
+	if _, role := utils.GetUser(c); input.Decision == "approve" && company.LimitsLocked && role != "admin" {
+		return c.String(http.StatusForbidden, `Изменение лимитов компании заблокировано, заявку может одобрить только администратор`)
+	}
+	switch input.Decision {
+	case "approve":
+		request.Status = limitRequestApproved
+		for i := range request.Items {
+			item := &request.Items[i]
+			item.ApprovedLimit = item.RequestedLimit
+			if limit, ok := approved[item.ID]; ok {
+				if limit > item.RequestedLimit {
+					return c.String(http.StatusBadRequest, "Одобренный лимит больше запрошенного")
+				}
+				item.ApprovedLimit = limit
+			}
+			if item.ApprovedLimit < item.RequestedLimit {
+				request.Status = limitRequestPartiallyApproved
+			}
+		}
+	case "reject":
+		request.Status = limitRequestRejected
+		if strings.TrimSpace(input.Comment) == "" {
+			return c.String(http.StatusBadRequest, "Укажите причину отказа")
+		}
+		for i := range request.Items {
+			request.Items[i].ApprovedLimit = 0
+		}
+	default:
+		return c.String(http.StatusBadRequest, "Некорректное решение: используйте approve или reject")
+	}
+
+	userID, _ := utils.GetUser(c)
+	now := time.Now()
+	request.DecisionComment = strings.TrimSpace(input.Comment)
+	request.DecidedByID = userID
+	request.DecidedAt = &now
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		decided := tx.Model(&model.LimitChangeRequest{}).Where("id = ? AND status = ?", request.ID, limitRequestPending).Updates(map[string]interface{}{
+			"status":           request.Status,
+			"decision_comment": request.DecisionComment,
+			"decided_by_id":    request.DecidedByID,
+			"decided_at":       request.DecidedAt,
+		})
+		if decided.Error != nil {
+			return decided.Error
+		}
+		if decided.RowsAffected == 0 {
+			return echo.NewHTTPError(http.StatusConflict, "Заявка уже рассмотрена")
+		}
+		for _, item := range request.Items {
+			if err := tx.Model(&model.LimitChangeRequestItem{}).Where("id = ?", item.ID).Update("approved_limit", item.ApprovedLimit).Error; err != nil {
+				return err
+			}
+			// the limit may have been changed since the request was submitted, approval never lowers it
+			current, err := currentLimit(tx, company.ID, item.Kind, item.TargetID)
+			if err != nil {
+				return err
+			}
+			if item.ApprovedLimit <= current {
+				continue
+			}
+			if err := utils.CheckAllocatedCapacity(tx, company.ID, item.Kind, item.TargetID, item.ApprovedLimit); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			if err := applyLimit(tx, &company, item.Kind, item.TargetID, item.ApprovedLimit); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.Company{}).Where("id = ?", company.ID).Update("members_limit", company.MembersLimit).Error; err != nil {
+			return err
+		}
+		details, _ := json.Marshal(request)
+		return logCompanyHistory(tx, c, company.ID, "limit_request_"+request.Status, string(details))
+	})
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// cancelLimitRequest withdraws a pending request
+func cancelLimitRequest(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	request, company, err := findLimitRequest(c, true)
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.LimitChangeRequest{}).
+			Where("id = ? AND status = ?", request.ID, limitRequestPending).
+			Update("status", limitRequestCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return echo.NewHTTPError(http.StatusConflict, "Заявка уже рассмотрена")
+		}
+		return logCompanyHistory(tx, c, company.ID, "limit_request_cancelled", fmt.Sprintf(`{"request_id":%q}`, request.ID))
+	})
+	if err != nil {
+		return companyHTTPError(c, err)
+	}
+	request.Status = limitRequestCancelled
+	return c.JSON(http.StatusOK, request)
+}
+
+// findLimitRequest loads the request of :id with items and checks access to its company
+func findLimitRequest(c echo.Context, editRequest bool) (model.LimitChangeRequest, model.Company, error) {
+	var request model.LimitChangeRequest
+	var company model.Company
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return request, company, echo.NewHTTPError(http.StatusBadRequest, `{"error":"invalid id"}`)
+	}
+	if err := db.Preload("Items").First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return request, company, echo.NewHTTPError(http.StatusNotFound, `Заявка не найдена`)
+		}
+		return request, company, err
+	}
+	if err := db.Preload("User").First(&company, request.CompanyID).Error; err != nil {
+		return request, company, echo.NewHTTPError(http.StatusNotFound, `Компания не найдена`)
+	}
+	if !checkRole(c, company, editRequest) {
+		return request, company, echo.NewHTTPError(http.StatusForbidden, `У пользователя недостаточно привелегий`)
+	}
+	return request, company, nil
+}
+
+// currentLimit returns the company limit for the target, zero when no limit row exists yet
+func currentLimit(tx *gorm.DB, companyID uuid.UUID, kind string, targetID uuid.UUID) (uint, error) {
+	var target interface{}
+	var limits *gorm.DB
+	switch kind {
+	case "accreditation":
+		target = &model.Accreditation{}
+		limits = tx.Model(&model.CompanyAccreditationLimit{}).Where("accreditation_id = ?", targetID)
+	case "event":
+		target = &model.Event{}
+		limits = tx.Model(&model.CompanyEventLimit{}).Where("event_id = ?", targetID)
+	case "gate":
+		target = &model.Gate{}
+		limits = tx.Model(&model.CompanyGateLimit{}).Where("gate_id = ?", targetID)
+	default:
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Неизвестный тип лимита: "+kind)
+	}
+	var exists int64
+	if err := tx.Model(target).Where("id = ?", targetID).Count(&exists).Error; err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		return 0, echo.NewHTTPError(http.StatusNotFound, "Объект лимита не найден")
+	}
+	var limit []uint
+	if err := limits.Where("company_id = ?", companyID).Limit(1).Pluck("limit", &limit).Error; err != nil {
+		return 0, err
+	}
+	if len(limit) == 0 {
+		return 0, nil
+	}
+	return limit[0], nil
+}
+
+// applyLimit sets the limit row, raising the company members limit like updateCompany does
+func applyLimit(tx *gorm.DB, company *model.Company, kind string, targetID uuid.UUID, limit uint) error {
+	if limit > company.MembersLimit {
+		company.MembersLimit = limit
+	}
+	switch kind {
+	case "accreditation":
+		var row model.CompanyAccreditationLimit
+		err := tx.Where("company_id = ? AND accreditation_id = ?", company.ID, targetID).First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.CompanyAccreditationLimit{CompanyID: company.ID, AccreditationID: targetID, Limit: limit}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&row).Update("limit", limit).Error
+	case "event":
+		var row model.CompanyEventLimit
+		err := tx.Where("company_id = ? AND event_id = ?", company.ID, targetID).First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.CompanyEventLimit{CompanyID: company.ID, EventID: targetID, Limit: limit}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&row).Update("limit", limit).Error
+	case "gate":
+		var row model.CompanyGateLimit
+		err := tx.Where("company_id = ? AND gate_id = ?", company.ID, targetID).First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.CompanyGateLimit{CompanyID: company.ID, GateID: targetID, Limit: limit}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&row).Update("limit", limit).Error
+	}
+	return fmt.Errorf("unknown limit kind %q", kind)
+}
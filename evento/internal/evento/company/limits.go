@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 func getCompanyLimits(c echo.Context) error {
@@ -44,7 +46,6 @@ func getCompanyLimits(c echo.Context) error {
 			if limit.AccreditationID == accreditation.ID {
 				var count int64
 				err = db.Model(&model.Member{}).Where("accreditation_id = ?", accreditation.ID).Where("company_id = ?", companyID).Count(&count).Error
-				fmt.Println("SUPER", accreditation.Name, count, limit.Limit)
 				if err != nil {
 					return c.String(http.StatusInternalServerError, err.Error())
 				}
@@ -126,7 +127,6 @@ func getCompanyLimits(c echo.Context) error {
 				if err != nil {
 					return c.String(http.StatusInternalServerError, err.Error())
 				}
-				fmt.Println("gate limit - count", int64(limit.Limit)-count)
 				if int64(limit.Limit)-count > 0 {
 					gateLimits = append(gateLimits, GateLimit{ID: gate.ID, Position: gate.Position, Name: fmt.Sprintf("%s (доступно: %d из %d)", gate.Name, int64(limit.Limit)-count, limit.Limit), Limit: limit.Limit - uint(count)})
 				}
@@ -152,3 +152,77 @@ func getCompanyLimits(c echo.Context) error {
 		GateLimits:   gateLimits,
 	})
 }
+
+// limitsChangeRequested reports whether the update would change any company limit
+func limitsChangeRequested(database *gorm.DB, company model.Company, companyIn model.CompanyIn) (bool, error) {
+	if (companyIn.MembersLimit != 0 && companyIn.MembersLimit != company.MembersLimit) ||
+		(companyIn.InEventMembersLimit != 0 && companyIn.InEventMembersLimit != company.InEventMembersLimit) ||
+		(companyIn.CarsLimit != 0 && companyIn.CarsLimit != company.CarsLimit) {
+		return true, nil
+	}
+	current := map[string]uint{}
+	var accreditationLimits []model.CompanyAccreditationLimit
+	if err := database.Where("company_id = ?", company.ID).Find(&accreditationLimits).Error; err != nil {
+		return false, err
+	}
+	for _, limit := range accreditationLimits {
+		current["accreditation:"+limit.AccreditationID.String()] = limit.Limit
+	}
+	var eventLimits []model.CompanyEventLimit
+	if err := database.Where("company_id = ?", company.ID).Find(&eventLimits).Error; err != nil {
+		return false, err
+	}
+	for _, limit := range eventLimits {
+		current["event:"+limit.EventID.String()] = limit.Limit
+	}
+	var gateLimits []model.CompanyGateLimit
+	if err := database.Where("company_id = ?", company.ID).Find(&gateLimits).Error; err != nil {
+		return false, err
+	}
+	for _, limit := range gateLimits {
+		current["gate:"+limit.GateID.String()] = limit.Limit
+	}
+	for prefix, requested := range map[string]map[string]uint{
+		"accreditation:": companyIn.Accreditations,
+		"event:":         companyIn.Events,
+		"gate:":          companyIn.Gates,
+	} {
+		for id, limit := range requested {
+			if current[prefix+strings.ToLower(id)] != limit {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// checkCompanyCapacity validates raised company limits against global capacity of accreditations, events and gates,
+// unknown ids are skipped and reported by the caller
+func checkCompanyCapacity(database *gorm.DB, companyID uuid.UUID, companyIn model.CompanyIn) error {
+	for kind, requested := range map[string]map[string]uint{
+		"accreditation": companyIn.Accreditations,
+		"event":         companyIn.Events,
+		"gate":          companyIn.Gates,
+	} {
+		for idStr, limit := range requested {
+			targetID, err := uuid.Parse(idStr)
+			if err != nil {
+				continue
+			}
+			current, err := currentLimit(database, companyID, kind, targetID)
+			if err != nil {
+				if _, ok := err.(*echo.HTTPError); ok {
+					continue
+				}
+				return err
+			}
+			if limit <= current {
+				continue
+			}
+			if err := utils.CheckAllocatedCapacity(database, companyID, kind, targetID, limit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
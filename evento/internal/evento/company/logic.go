@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
@@ -86,7 +87,18 @@ func freezeCompany(c echo.Context) error {
 		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.Bind(&request); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 	user.Frozen = !user.Frozen
+	user.FrozenReason = ""
+	if user.Frozen {
+		user.FrozenReason = strings.TrimSpace(request.Reason)
+	}
 	db.Save(&user)
+	logCompanyHistory(db, c, company.ID, "freeze", fmt.Sprintf(`{"frozen":%t,"reason":%q}`, user.Frozen, user.FrozenReason))
 	return c.NoContent(http.StatusNoContent)
 }
@@ -2,7 +2,7 @@ package evento
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -11,12 +11,13 @@ import (
 
 	"github.com/eugenetolok/evento/internal/evento/aiassistant"
 	"github.com/eugenetolok/evento/internal/evento/emailtemplate"
+	"github.com/eugenetolok/evento/internal/evento/frontendsettings"
+	"github.com/eugenetolok/evento/pkg/imaging"
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/metrics"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/smtp"
 	"github.com/eugenetolok/evento/pkg/utils"
-	"github.com/manifoldco/promptui"
-	"golang.org/x/crypto/bcrypt"
-	"gopkg.in/yaml.v3"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -25,88 +26,156 @@ var (
 	db *gorm.DB
 	// yaml settings
 	appSettings model.AppSettings
+	configPath  = "app.yaml"
 
 	photoStorageDir string
 )
 
-func InitEvento(f model.Flags) {
-	// flags
-	if f.ShowYamlStruct {
-		yamlFile, _ := yaml.Marshal(&appSettings)
-		fmt.Println(string(yamlFile))
-		os.Exit(0)
+// migratedModels lists every table managed by migrate/drop commands.
+func migratedModels() []interface{} {
+	return []interface{}{
+		model.User{}, model.Member{}, model.Company{}, model.Auto{}, model.Accreditation{}, model.Event{}, model.Gate{}, model.CompanyAccreditationLimit{}, model.CompanyEventLimit{}, model.CompanyGateLimit{}, model.MemberPass{}, model.MemberPrint{}, model.MemberHistory{}, model.CompanyHistory{}, model.AutoHistory{}, model.BadgeTemplate{}, model.EmailTemplate{}, model.FrontendSettingsOverride{}, model.ScheduledAction{}, model.CompanyDeadlineOverride{}, model.LimitChangeRequest{}, model.LimitChangeRequestItem{}, model.PrintJob{}, model.BadgeTemplateVersion{}, model.CompanyBadgeTemplate{}, model.CredentialKey{}, model.MemberBarcode{}, model.BangleType{}, model.BanglePoint{}, model.BangleStock{}, model.BangleIssue{}, model.MemberCredential{}, model.PrintOverrideApproval{},
 	}
-	// init configs
-	updateConfig()
-	smtp.InitConfig(appSettings.MailSettings)
+}
 
-	// Resolve and prepare photo storage directory
-	photoStorageDir = appSettings.SiteSettings.PhotoStoragePath
-	// If the path is relative, make it absolute based on the working directory
-	if !filepath.IsAbs(photoStorageDir) {
-		photoStorageDir = filepath.Join(utils.WorkDir(), photoStorageDir)
+// LoadConfig reads settings from the yaml file and applies defaults and env overrides.
+func LoadConfig(path string) error {
+	if strings.TrimSpace(path) != "" {
+		configPath = path
 	}
-	log.Printf("Photo storage directory: %s", photoStorageDir)
-	// Create the directory if it doesn't exist
-	if err := os.MkdirAll(photoStorageDir, 0755); err != nil { // 0755 permissions
-		panic(fmt.Sprintf("failed to create photo storage directory '%s': %v", photoStorageDir, err))
+	if err := updateConfig(); err != nil {
+		return err
 	}
-	// init db
+	logger.Init(appSettings.SiteSettings.LogFormat, appSettings.SiteSettings.Debug)
+	return nil
+}
+
+// Settings returns the loaded application settings.
+func Settings() model.AppSettings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return appSettings
+}
+
+// ValidateConfig checks the loaded settings the same way the server does on startup.
+func ValidateConfig() error {
+	return validateSecuritySettings(&appSettings)
+}
+
+// OpenDatabase connects to the configured sqlite database.
+func OpenDatabase() (*gorm.DB, error) {
 	dsn := appSettings.SiteSettings.DBPath
-	fmt.Println("db_path", appSettings.SiteSettings.DBPath)
+	slog.Info("opening database", "db_path", dsn)
 	var err error
 	db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	if err != nil {
-		panic("failed to connect database")
-	}
-	if f.DropTable {
-		db.Migrator().DropTable(
-			model.User{}, model.Member{}, model.Company{}, model.Auto{}, model.Accreditation{}, model.Event{}, model.Gate{}, model.CompanyAccreditationLimit{}, model.CompanyEventLimit{}, model.CompanyGateLimit{}, model.MemberPass{}, model.MemberPrint{}, model.MemberHistory{}, model.CompanyHistory{}, model.AutoHistory{}, model.BadgeTemplate{}, model.EmailTemplate{})
-		log.Println("All tables are dropped")
-		os.Exit(0)
-	}
-	if f.Migrate {
-		db.AutoMigrate(
-			model.User{}, model.Member{}, model.Company{}, model.Auto{}, model.Accreditation{}, model.Event{}, model.Gate{}, model.CompanyAccreditationLimit{}, model.CompanyEventLimit{}, model.CompanyGateLimit{}, model.MemberPass{}, model.MemberPrint{}, model.MemberHistory{}, model.CompanyHistory{}, model.AutoHistory{}, model.BadgeTemplate{}, model.EmailTemplate{})
-		log.Println("All tables are migrated")
-		os.Exit(0)
-	}
-	if f.AddUser {
-		var user model.User
-		user.Username, user.Password, user.Role = promptUser()
-		// Hash the password before saving
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-		if err != nil {
-			log.Println("password hashing failed", err)
-			os.Exit(1)
-		}
-		user.Password = string(hashedPassword) // Store the hash
-		if err := db.Create(&user).Error; err != nil {
-			log.Println("user creation failed", err)
-			os.Exit(1)
-		}
-		os.Exit(0)
+		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
+	return db, nil
+}
+
+// Migrate creates or updates all tables.
+func Migrate() error {
+	return db.AutoMigrate(migratedModels()...)
+}
+
+// DropTables drops all tables. WARNING: all data is lost.
+func DropTables() error {
+	return db.Migrator().DropTable(migratedModels()...)
+}
+
+// PhotoStorageDir returns the absolute photo storage directory.
+func PhotoStorageDir() string {
+	dir := appSettings.SiteSettings.PhotoStoragePath
+	// If the path is relative, make it absolute based on the working directory
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(utils.WorkDir(), dir)
+	}
+	return dir
+}
+
+// PrepareServer validates settings and runs startup sync before serving requests.
+func PrepareServer() error {
 	if err := validateSecuritySettings(&appSettings); err != nil {
-		log.Fatal(err)
+		return err
+	}
+	smtp.InitConfig(appSettings.MailSettings)
+
+	// Resolve and prepare photo storage directory
+	photoStorageDir = PhotoStorageDir()
+	slog.Info("photo storage directory", "path", photoStorageDir)
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(photoStorageDir, 0755); err != nil { // 0755 permissions
+		return fmt.Errorf("failed to create photo storage directory '%s': %w", photoStorageDir, err)
 	}
 	ensureUserFreezeScheduleColumns()
+	ensureHistoryRequestIDColumns()
+	ensureScheduledActionSchema()
+	ensureRegistrationDeadlineSchema()
+	ensureLimitChangeRequestSchema()
+	ensureCapacitySchema()
+	ensurePrintJobSchema()
+	ensureBadgeTemplateSchema()
+	ensureCredentialKeySchema()
+	ensureMemberBarcodeSchema()
+	ensureBangleSchema()
+	ensureMemberCredentialSchema()
 	syncDerivedCompanyFieldsOnce()
 	syncEmptyMemberBarcodesOnce()
 	if err := aiassistant.EnsureReadOnlyViews(db); err != nil {
-		log.Fatalf("ai assistant views init failed: %v", err)
+		return fmt.Errorf("ai assistant views init failed: %w", err)
 	}
 	if err := emailtemplate.EnsureAndLoad(db); err != nil {
-		log.Fatalf("email templates init failed: %v", err)
+		return fmt.Errorf("email templates init failed: %w", err)
+	}
+	if err := frontendsettings.EnsureAndLoad(db); err != nil {
+		return fmt.Errorf("frontend settings init failed: %w", err)
 	}
+	if err := metrics.RegisterGormCallbacks(db); err != nil {
+		return fmt.Errorf("db metrics init failed: %w", err)
+	}
+	if err := registerJobs(); err != nil {
+		return fmt.Errorf("jobs init failed: %w", err)
+	}
+	return nil
+}
+
+func updateConfig() error {
+	settings, yamlPaths, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	settingsMu.Lock()
+	appSettings = settings
+	settingsYAMLPaths = yamlPaths
+	settingsPendingRestart = nil
+	settingsLoadedAt = time.Now()
+	settingsMu.Unlock()
+	return nil
 }
 
-func updateConfig() {
-	if !utils.UnmarshalYaml("app.yaml", &appSettings) {
-		log.Println("settings invalid")
+// loadSettings reads the settings file and applies defaults and env overrides.
+// Also returns the set of setting paths present in the file.
+func loadSettings() (model.AppSettings, map[string]bool, error) {
+	var settings model.AppSettings
+	yamlPaths := map[string]bool{}
+	path := utils.ResolvePath(configPath)
+	if utils.FileNotExist(path) == nil {
+		if !utils.UnmarshalYaml(path, &settings) {
+			return settings, nil, fmt.Errorf("settings invalid: unable to parse %s", path)
+		}
+		var raw map[string]interface{}
+		if utils.UnmarshalYaml(path, &raw) {
+			flattenSettings("", raw, func(key string, _ interface{}) {
+				yamlPaths[key] = true
+			})
+		}
+	} else {
+		slog.Warn("settings file not found, using defaults", "path", path)
 	}
-	applyDefaultAppSettings(&appSettings)
-	applyEnvOverrides(&appSettings)
+	applyDefaultAppSettings(&settings)
+	applyEnvOverrides(&settings)
+	return settings, yamlPaths, nil
 }
 
 func applyDefaultAppSettings(settings *model.AppSettings) {
@@ -115,6 +184,9 @@ func applyDefaultAppSettings(settings *model.AppSettings) {
 	if settings.SiteSettings.DBPath == "" {
 		settings.SiteSettings.DBPath = "test.db"
 	}
+	if settings.SiteSettings.LogFormat == "" {
+		settings.SiteSettings.LogFormat = "json"
+	}
 	if settings.SiteSettings.PhotoStoragePath == "" {
 		settings.SiteSettings.PhotoStoragePath = "photos"
 	}
@@ -270,12 +342,54 @@ func applyDefaultAppSettings(settings *model.AppSettings) {
 	if settings.AIAssistantSettings.LLMTemperature > 1 {
 		settings.AIAssistantSettings.LLMTemperature = 0.1
 	}
+	if settings.BadgeSettings.FontsDir == "" {
+		settings.BadgeSettings.FontsDir = "fonts"
+	}
+	if settings.BadgeSettings.DefaultFont == "" {
+		settings.BadgeSettings.DefaultFont = "DejaVuSans.ttf"
+	}
+	if settings.BadgeSettings.PageSize == "" {
+		settings.BadgeSettings.PageSize = "badge"
+	}
+	if settings.BadgeSettings.BadgeWidth <= 0 {
+		settings.BadgeSettings.BadgeWidth = 105
+	}
+	if settings.BadgeSettings.BadgeHeight <= 0 {
+		settings.BadgeSettings.BadgeHeight = 148
+	}
+	if settings.BadgeSettings.Margin < 0 {
+		settings.BadgeSettings.Margin = 0
+	}
+	if settings.BadgeSettings.Gap < 0 {
+		settings.BadgeSettings.Gap = 0
+	}
+	if settings.PhotoSettings.AspectWidth <= 0 || settings.PhotoSettings.AspectHeight <= 0 {
+		settings.PhotoSettings.AspectWidth = 3
+		settings.PhotoSettings.AspectHeight = 4
+	}
+	if settings.PhotoSettings.MaxWidth <= 0 {
+		settings.PhotoSettings.MaxWidth = 600
+	}
+	if settings.PhotoSettings.Quality <= 0 || settings.PhotoSettings.Quality > 100 {
+		settings.PhotoSettings.Quality = 85
+	}
+	if settings.PhotoSettings.ThumbnailSize <= 0 {
+		settings.PhotoSettings.ThumbnailSize = 160
+	}
+	if settings.PhotoSettings.FaceCheck == "" {
+		settings.PhotoSettings.FaceCheck = "none"
+	}
 }
 
 func applyEnvOverrides(settings *model.AppSettings) {
 	applyStringEnv("EVENTO_DB_PATH", &settings.SiteSettings.DBPath)
 	applyStringEnv("EVENTO_PHOTO_STORAGE_PATH", &settings.SiteSettings.PhotoStoragePath)
 	applyStringEnv("EVENTO_SECRET_JWT", &settings.SiteSettings.SecretJWT)
+	applyStringEnv("EVENTO_METRICS_TOKEN", &settings.SiteSettings.MetricsToken)
+	applyStringEnv("EVENTO_LOG_FORMAT", &settings.SiteSettings.LogFormat)
+	if debugRaw := strings.TrimSpace(os.Getenv("EVENTO_DEBUG")); debugRaw != "" {
+		settings.SiteSettings.Debug = strings.EqualFold(debugRaw, "true") || debugRaw == "1"
+	}
 
 	applyStringEnv("EVENTO_SMTP_FROM_NAME", &settings.MailSettings.FromName)
 	applyStringEnv("EVENTO_SMTP_FROM", &settings.MailSettings.From)
@@ -298,6 +412,8 @@ func applyEnvOverrides(settings *model.AppSettings) {
 	applyStringEnv("EVENTO_OPENROUTER_API_KEY", &settings.AIAssistantSettings.OpenRouterAPIKey)
 	applyStringEnv("EVENTO_OPENROUTER_REFERER", &settings.AIAssistantSettings.OpenRouterReferer)
 	applyStringEnv("EVENTO_OPENROUTER_APP_TITLE", &settings.AIAssistantSettings.OpenRouterAppTitle)
+	applyStringEnv("EVENTO_BADGE_FONTS_DIR", &settings.BadgeSettings.FontsDir)
+	applyStringEnv("EVENTO_PHOTO_FACE_CHECK", &settings.PhotoSettings.FaceCheck)
 
 	if enabledRaw := strings.TrimSpace(os.Getenv("EVENTO_AI_ENABLED")); enabledRaw != "" {
 		settings.AIAssistantSettings.Enabled = strings.EqualFold(enabledRaw, "true") || enabledRaw == "1"
@@ -306,7 +422,7 @@ func applyEnvOverrides(settings *model.AppSettings) {
 	if tempRaw := strings.TrimSpace(os.Getenv("EVENTO_AI_TEMPERATURE")); tempRaw != "" {
 		parsed, err := strconv.ParseFloat(tempRaw, 64)
 		if err != nil {
-			log.Printf("invalid float env EVENTO_AI_TEMPERATURE=%q: %v", tempRaw, err)
+			slog.Warn("invalid float env", "key", "EVENTO_AI_TEMPERATURE", "value", tempRaw, "error", err)
 		} else {
 			settings.AIAssistantSettings.LLMTemperature = parsed
 		}
@@ -330,7 +446,7 @@ func applyIntEnv(key string, target *int) {
 	}
 	parsed, err := strconv.Atoi(value)
 	if err != nil {
-		log.Printf("invalid integer env %s=%q: %v", key, value, err)
+		slog.Warn("invalid integer env", "key", key, "value", value, "error", err)
 		return
 	}
 	*target = parsed
@@ -372,6 +488,9 @@ func validateSecuritySettings(settings *model.AppSettings) error {
 	if settings.SiteSettings.AuthRateLimitBurst <= 0 {
 		return fmt.Errorf("site_settings.auth_rate_limit_burst must be greater than 0")
 	}
+	if _, err := imaging.NewFaceDetector(settings.PhotoSettings.FaceCheck); err != nil {
+		return fmt.Errorf("photo_settings.face_check must be \"none\" or \"skin\"")
+	}
 	if settings.AIAssistantSettings.Enabled {
 		if strings.ToLower(strings.TrimSpace(settings.AIAssistantSettings.Provider)) != "openrouter" {
 			return fmt.Errorf("ai_assistant.provider must be \"openrouter\"")
@@ -390,48 +509,9 @@ func validateSecuritySettings(settings *model.AppSettings) error {
 }
 
 func GetCORSAllowOrigins() []string {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
 	origins := make([]string, len(appSettings.SiteSettings.CORSAllowOrigins))
 	copy(origins, appSettings.SiteSettings.CORSAllowOrigins)
 	return origins
 }
-
-// Function to prompt user for username and password using promptui
-func promptUser() (string, string, string) {
-	// Create prompts for username and password
-	usernamePrompt := promptui.Prompt{
-		Label: "Enter username",
-	}
-
-	passwordPrompt := promptui.Prompt{
-		Label: "Enter password",
-		Mask:  '*',
-	}
-
-	rolePrompt := promptui.Select{
-		Label: "Select role",
-		Items: []string{"admin", "editor", "company", "operator", "monitoring"},
-	}
-
-	// Prompt user for username
-	username, err := usernamePrompt.Run()
-	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		os.Exit(1)
-	}
-
-	// Prompt user for password
-	password, err := passwordPrompt.Run()
-	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		os.Exit(1)
-	}
-
-	// Prompt user for username
-	_, role, err := rolePrompt.Run()
-	if err != nil {
-		fmt.Println("Prompt failed:", err)
-		os.Exit(1)
-	}
-
-	return strings.TrimSpace(username), strings.TrimSpace(password), strings.TrimSpace(role)
-}
@@ -0,0 +1,214 @@
+package evento
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eugenetolok/evento/internal/evento/member"
+	"github.com/eugenetolok/evento/internal/evento/report"
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+var (
+	settingsMu sync.RWMutex
+	// setting paths present in the settings file, used to report value sources
+	settingsYAMLPaths map[string]bool
+	// changed paths that are applied only after restart
+	settingsPendingRestart []string
+	settingsLoadedAt       time.Time
+
+	authLimiterStore = &reloadableLimiterStore{}
+)
+
+// reloadablePaths are setting paths (or prefixes) applied without restart
+var reloadablePaths = []string{
+	"site_settings.debug",
+	"site_settings.cors_allow_origins",
+	"site_settings.auth_rate_limit_rps",
+	"site_settings.auth_rate_limit_burst",
+	"frontend_settings",
+	"report_settings",
+	"badge_settings",
+}
+
+func isReloadablePath(key string) bool {
+	for _, prefix := range reloadablePaths {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadConfig re-reads the settings file and applies reloadable sections.
+// Invalid settings are rejected and the running configuration is kept.
+func ReloadConfig() error {
+	loaded, yamlPaths, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	if err := validateSecuritySettings(&loaded); err != nil {
+		return fmt.Errorf("reload rejected: %w", err)
+	}
+
+	settingsMu.Lock()
+	next := appSettings
+	next.SiteSettings.Debug = loaded.SiteSettings.Debug
+	next.SiteSettings.CORSAllowOrigins = loaded.SiteSettings.CORSAllowOrigins
+	next.SiteSettings.AuthRateLimitRPS = loaded.SiteSettings.AuthRateLimitRPS
+	next.SiteSettings.AuthRateLimitBurst = loaded.SiteSettings.AuthRateLimitBurst
+	next.FrontendSettings = loaded.FrontendSettings
+	next.ReportSettings = loaded.ReportSettings
+	next.BadgeSettings = loaded.BadgeSettings
+	appSettings = next
+
+	mergedPaths := map[string]bool{}
+	for key := range settingsYAMLPaths {
+		if !isReloadablePath(key) {
+			mergedPaths[key] = true
+		}
+	}
+	for key := range yamlPaths {
+		if isReloadablePath(key) {
+			mergedPaths[key] = true
+		}
+	}
+	settingsYAMLPaths = mergedPaths
+	settingsPendingRestart = diffSettingPaths(next, loaded)
+	settingsLoadedAt = time.Now()
+	pending := settingsPendingRestart
+	settingsMu.Unlock()
+
+	logger.SetDebug(next.SiteSettings.Debug)
+	report.SetDashboardSettings(next.ReportSettings.Dashboard)
+	member.SetBadgeSettings(next.BadgeSettings)
+	authLimiterStore.configure(next.SiteSettings.AuthRateLimitRPS, next.SiteSettings.AuthRateLimitBurst)
+	if len(pending) > 0 {
+		slog.Warn("settings reloaded, restart required to apply some changes", "pending_restart", pending)
+	} else {
+		slog.Info("settings reloaded")
+	}
+	return nil
+}
+
+// WatchConfig reloads settings on SIGHUP or when the settings file modification time changes
+func WatchConfig(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	file := utils.ResolvePath(configPath)
+	lastModified := fileModTime(file)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("SIGHUP received, reloading settings")
+			case <-ticker.C:
+				modified := fileModTime(file)
+				if modified.Equal(lastModified) {
+					continue
+				}
+				lastModified = modified
+				slog.Info("settings file changed, reloading", "path", file)
+			}
+			if err := ReloadConfig(); err != nil {
+				slog.Error("settings reload failed", "error", err)
+			}
+		}
+	}()
+}
+
+func fileModTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// diffSettingPaths lists paths whose values differ between two settings
+func diffSettingPaths(current, loaded model.AppSettings) []string {
+	currentValues := settingsValues(current)
+	loadedValues := settingsValues(loaded)
+	changed := map[string]bool{}
+	for key, value := range loadedValues {
+		if !reflect.DeepEqual(currentValues[key], value) {
+			changed[key] = true
+		}
+	}
+	for key := range currentValues {
+		if _, ok := loadedValues[key]; !ok {
+			changed[key] = true
+		}
+	}
+	result := make([]string, 0, len(changed))
+	for key := range changed {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// corsOriginAllowed checks the origin against the current allow list,
+// entries may contain wildcards like https://*.example.com
+func corsOriginAllowed(origin string) (bool, error) {
+	for _, allowed := range GetCORSAllowOrigins() {
+		if allowed == "*" || allowed == origin {
+			return true, nil
+		}
+		if strings.Contains(allowed, "*") {
+			if matched, _ := path.Match(allowed, origin); matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// reloadableLimiterStore swaps the underlying memory store when limits change
+type reloadableLimiterStore struct {
+	mu    sync.RWMutex
+	store *middleware.RateLimiterMemoryStore
+	rps   int
+	burst int
+}
+
+func (s *reloadableLimiterStore) configure(rps, burst int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil && s.rps == rps && s.burst == burst {
+		return
+	}
+	s.rps, s.burst = rps, burst
+	s.store = middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(rps),
+		Burst:     burst,
+		ExpiresIn: 3 * time.Minute,
+	})
+}
+
+// Allow implements middleware.RateLimiterStore
+func (s *reloadableLimiterStore) Allow(identifier string) (bool, error) {
+	s.mu.RLock()
+	store := s.store
+	s.mu.RUnlock()
+	return store.Allow(identifier)
+}
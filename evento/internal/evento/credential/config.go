@@ -0,0 +1,26 @@
+package credential
+
+import (
+	"log/slog"
+
+	"github.com/eugenetolok/evento/pkg/utils"
+	echojwt "github.com/labstack/echo-jwt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// InitCredentials entry point of signed credentials, public keys are open for offline scanners
+func InitCredentials(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config) {
+	db = dbInstance
+	signingKey, _ := jwtConfig.SigningKey.([]byte)
+	setKeyCipher(signingKey)
+	if err := sealStoredKeys(); err != nil {
+		slog.Error("credential keys are not encrypted", "error", err)
+	}
+	g.GET("/keys", getKeys)
+	g.POST("/keys/rotate", rotateKey, echojwt.WithConfig(jwtConfig), utils.RoleMiddleware([]string{"admin"}))
+	g.DELETE("/keys/:kid", deleteKey, echojwt.WithConfig(jwtConfig), utils.RoleMiddleware([]string{"admin"}))
+	g.POST("/verify", verifyCredential, echojwt.WithConfig(jwtConfig), utils.RoleMiddleware([]string{"admin", "operator"}))
+}
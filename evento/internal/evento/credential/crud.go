@@ -0,0 +1,80 @@
+package credential
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/labstack/echo/v4"
+)
+
+type keyResponse struct {
+	KeyID     string     `json:"key_id"`
+	Algorithm string     `json:"algorithm"`
+	PublicKey string     `json:"public_key"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at"`
+}
+
+// getKeys publishes the public keys, scanners cache them to verify credentials offline
+func getKeys(c echo.Context) error {
+	var keys []model.CredentialKey
+	if err := db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	response := make([]keyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, keyResponse{
+			KeyID:     key.KeyID,
+			Algorithm: "Ed25519",
+			PublicKey: key.PublicKey,
+			Active:    key.Active,
+			CreatedAt: key.CreatedAt,
+			RetiredAt: key.RetiredAt,
+		})
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, response)
+}
+
+func rotateKey(c echo.Context) error {
+	key, err := rotate()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, keyResponse{
+		KeyID:     key.KeyID,
+		Algorithm: "Ed25519",
+		PublicKey: key.PublicKey,
+		Active:    key.Active,
+		CreatedAt: key.CreatedAt,
+	})
+}
+
+// deleteKey removes a key, every credential signed with it stops verifying
+func deleteKey(c echo.Context) error {
+	result := db.Unscoped().Where("key_id = ?", c.Param("kid")).Delete(&model.CredentialKey{})
+	if result.Error != nil {
+		return c.String(http.StatusInternalServerError, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return c.String(http.StatusNotFound, "Ключ не найден")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func verifyCredential(c echo.Context) error {
+	var input struct {
+		Credential string `json:"credential"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	claims, err := Verify(input.Credential)
+	response := map[string]interface{}{"valid": err == nil, "claims": claims}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,76 @@
+package credential
+
+import (
+	"errors"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/credential"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+)
+
+// ErrBarcodeReplaced denies credentials issued for a barcode the member no longer has
+var ErrBarcodeReplaced = errors.New("credential barcode was replaced")
+
+// defaultValidity is the validity of credentials of members without events
+const defaultValidity = 30 * 24 * time.Hour
+
+// Issue signs a credential for the member, gates are the member and accreditation gates,
+// the validity window spans the member events
+func Issue(memberID uuid.UUID) (string, credential.Claims, error) {
+	var member model.Member
+	if err := db.Preload("Accreditation.Gates").Preload("Gates").Preload("Events").First(&member, memberID).Error; err != nil {
+		return "", credential.Claims{}, err
+	}
+	key, private, err := activeKey()
+	if err != nil {
+		return "", credential.Claims{}, err
+	}
+	claims := credential.Claims{
+		KeyID:           key.KeyID,
+		MemberID:        member.ID,
+		AccreditationID: member.AccreditationID,
+		Fingerprint:     credential.Fingerprint(member.Barcode),
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, gates := range [][]model.Gate{member.Gates, member.Accreditation.Gates} {
+		for _, gate := range gates {
+			if !seen[gate.ID] {
+				seen[gate.ID] = true
+				claims.Gates = append(claims.Gates, gate.ID)
+			}
+		}
+	}
+	for _, event := range member.Events {
+		if claims.NotBefore.IsZero() || event.TimeStart.Before(claims.NotBefore) {
+			claims.NotBefore = event.TimeStart
+		}
+		if event.TimeEnd.After(claims.ExpiresAt) {
+			claims.ExpiresAt = event.TimeEnd
+		}
+	}
+	if claims.NotBefore.IsZero() || !claims.ExpiresAt.After(claims.NotBefore) {
+		claims.NotBefore = time.Now()
+		claims.ExpiresAt = claims.NotBefore.Add(defaultValidity)
+	}
+	claims.NotBefore = claims.NotBefore.Truncate(time.Second).UTC()
+	claims.ExpiresAt = claims.ExpiresAt.Truncate(time.Second).UTC()
+	code, err := credential.Sign(claims, private)
+	return code, claims, err
+}
+
+// Verify checks a scanned credential against the published keys and the current member barcode
+func Verify(code string) (credential.Claims, error) {
+	claims, err := credential.Verify(code, PublicKey, time.Now())
+	if err != nil {
+		return claims, err
+	}
+	var member model.Member
+	if err := db.Select("id", "barcode").First(&member, claims.MemberID).Error; err != nil {
+		return claims, err
+	}
+	if credential.Fingerprint(member.Barcode) != claims.Fingerprint {
+		return claims, ErrBarcodeReplaced
+	}
+	return claims, nil
+}
@@ -0,0 +1,147 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// keyMutex keeps concurrent issues from creating several active keys
+var keyMutex sync.Mutex
+
+// encryptedPrefix marks private keys sealed with keyCipher, the database never holds them in the clear
+const encryptedPrefix = "enc:"
+
+// keyCipher seals private keys with a key derived from secret_jwt, it is set by InitCredentials
+var keyCipher cipher.AEAD
+
+// setKeyCipher derives the AES-256-GCM key of private keys from the secret, a derived key is always 32 bytes
+func setKeyCipher(secret []byte) {
+	block, _ := aes.NewCipher(utils.DeriveKey(secret, "credential-keys"))
+	keyCipher, _ = cipher.NewGCM(block)
+}
+
+// sealPrivateKey encrypts the private key for storage
+func sealPrivateKey(private ed25519.PrivateKey) (string, error) {
+	nonce := make([]byte, keyCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := keyCipher.Seal(nonce, nonce, private, nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openPrivateKey decrypts a stored private key, keys stored before encryption are plain base64
+func openPrivateKey(stored string) (ed25519.PrivateKey, error) {
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		private, err := base64.StdEncoding.DecodeString(stored)
+		return ed25519.PrivateKey(private), err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedPrefix))
+	if err != nil || len(sealed) < keyCipher.NonceSize() {
+		return nil, errors.New("malformed sealed key")
+	}
+	nonceSize := keyCipher.NonceSize()
+	private, err := keyCipher.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	return ed25519.PrivateKey(private), err
+}
+
+// sealStoredKeys encrypts private keys stored in the clear by earlier versions
+func sealStoredKeys() error {
+	var keys []model.CredentialKey
+	if err := db.Where("private_key NOT LIKE ?", encryptedPrefix+"%").Find(&keys).Error; err != nil {
+		return err
+	}
+	for _, key := range keys {
+		private, err := openPrivateKey(key.PrivateKey)
+		if err != nil || len(private) != ed25519.PrivateKeySize {
+			return errors.New("credential key " + key.KeyID + " is damaged")
+		}
+		sealed, err := sealPrivateKey(private)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&model.CredentialKey{}).Where("id = ?", key.ID).Update("private_key", sealed).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeKey returns the signing key, the first key is created on demand
+func activeKey() (model.CredentialKey, ed25519.PrivateKey, error) {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	var key model.CredentialKey
+	err := db.Where("active = ?", true).Order("created_at DESC").First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		key, err = createKey(db)
+	}
+	if err != nil {
+		return key, nil, err
+	}
+	private, err := openPrivateKey(key.PrivateKey)
+	if err != nil || len(private) != ed25519.PrivateKeySize {
+		return key, nil, errors.New("credential key " + key.KeyID + " can not be decrypted, secret_jwt was changed or the key is damaged: rotate the keys")
+	}
+	return key, private, nil
+}
+
+func createKey(tx *gorm.DB) (model.CredentialKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return model.CredentialKey{}, err
+	}
+	sealed, err := sealPrivateKey(private)
+	if err != nil {
+		return model.CredentialKey{}, err
+	}
+	key := model.CredentialKey{
+		KeyID:      utils.GenerateRandomHex(4),
+		PublicKey:  base64.StdEncoding.EncodeToString(public),
+		PrivateKey: sealed,
+		Active:     true,
+	}
+	return key, tx.Create(&key).Error
+}
+
+// rotate retires the active key and creates a new one, retired keys keep verifying
+func rotate() (model.CredentialKey, error) {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+	var key model.CredentialKey
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&model.CredentialKey{}).Where("active = ?", true).
+			Updates(map[string]interface{}{"active": false, "retired_at": now}).Error; err != nil {
+			return err
+		}
+		var err error
+		key, err = createKey(tx)
+		return err
+	})
+	return key, err
+}
+
+// PublicKey looks up a published key by id, deleted keys are unknown
+func PublicKey(keyID string) (ed25519.PublicKey, bool) {
+	var key model.CredentialKey
+	if err := db.Where("key_id = ?", keyID).First(&key).Error; err != nil {
+		return nil, false
+	}
+	public, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return nil, false
+	}
+	return ed25519.PublicKey(public), true
+}
@@ -0,0 +1,186 @@
+package evento
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/internal/evento/frontendsettings"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// envSettingPaths maps env overrides from applyEnvOverrides to setting paths
+var envSettingPaths = map[string]string{
+	"EVENTO_DB_PATH":               "site_settings.db_path",
+	"EVENTO_PHOTO_STORAGE_PATH":    "site_settings.photo_storage_path",
+	"EVENTO_SECRET_JWT":            "site_settings.secret_jwt",
+	"EVENTO_METRICS_TOKEN":         "site_settings.metrics_token",
+	"EVENTO_LOG_FORMAT":            "site_settings.log_format",
+	"EVENTO_DEBUG":                 "site_settings.debug",
+	"EVENTO_CORS_ALLOW_ORIGINS":    "site_settings.cors_allow_origins",
+	"EVENTO_AUTH_RATE_LIMIT_RPS":   "site_settings.auth_rate_limit_rps",
+	"EVENTO_AUTH_RATE_LIMIT_BURST": "site_settings.auth_rate_limit_burst",
+	"EVENTO_SMTP_FROM_NAME":        "mail_settings.from_name",
+	"EVENTO_SMTP_FROM":             "mail_settings.from",
+	"EVENTO_SMTP_HOST":             "mail_settings.smtp",
+	"EVENTO_SMTP_PORT":             "mail_settings.port",
+	"EVENTO_SMTP_USER":             "mail_settings.user",
+	"EVENTO_SMTP_PASSWORD":         "mail_settings.password",
+	"EVENTO_SMTP_DOMAIN":           "mail_settings.domain",
+	"EVENTO_SMTP_CITY":             "mail_settings.city",
+	"EVENTO_AI_ENABLED":            "ai_assistant.enabled",
+	"EVENTO_AI_PROVIDER":           "ai_assistant.provider",
+	"EVENTO_AI_TEMPERATURE":        "ai_assistant.llm_temperature",
+	"EVENTO_AI_LLM_TIMEOUT_MS":     "ai_assistant.llm_timeout_ms",
+	"EVENTO_AI_QUERY_TIMEOUT_MS":   "ai_assistant.query_timeout_ms",
+	"EVENTO_AI_MAX_ROWS":           "ai_assistant.max_rows",
+	"EVENTO_OPENROUTER_BASE_URL":   "ai_assistant.openrouter_base_url",
+	"EVENTO_OPENROUTER_MODEL":      "ai_assistant.openrouter_model",
+	"EVENTO_OPENROUTER_API_KEY":    "ai_assistant.openrouter_api_key",
+	"EVENTO_OPENROUTER_REFERER":    "ai_assistant.openrouter_referer",
+	"EVENTO_OPENROUTER_APP_TITLE":  "ai_assistant.openrouter_app_title",
+	"EVENTO_BADGE_FONTS_DIR":       "badge_settings.fonts_dir",
+	"EVENTO_PHOTO_FACE_CHECK":      "photo_settings.face_check",
+}
+
+// secretSettingPaths are never shown in the effective configuration
+var secretSettingPaths = map[string]bool{
+	"site_settings.secret_jwt":        true,
+	"site_settings.admin_token":       true,
+	"site_settings.metrics_token":     true,
+	"mail_settings.password":          true,
+	"ai_assistant.openrouter_api_key": true,
+}
+
+const redactedValue = "[redacted]"
+
+type effectiveSetting struct {
+	Path       string      `json:"path"`
+	Value      interface{} `json:"value"`
+	Source     string      `json:"source"`
+	Reloadable bool        `json:"reloadable"`
+}
+
+type effectiveConfigResponse struct {
+	ConfigPath     string             `json:"config_path"`
+	LoadedAt       time.Time          `json:"loaded_at"`
+	PendingRestart []string           `json:"pending_restart"`
+	Settings       []effectiveSetting `json:"settings"`
+}
+
+func effectiveConfig(c echo.Context) error {
+	settingsMu.RLock()
+	current := appSettings
+	yamlPaths := settingsYAMLPaths
+	response := effectiveConfigResponse{
+		ConfigPath:     utils.ResolvePath(configPath),
+		LoadedAt:       settingsLoadedAt,
+		PendingRestart: append([]string{}, settingsPendingRestart...),
+	}
+	settingsMu.RUnlock()
+
+	envPaths := map[string]bool{}
+	for key, settingPath := range envSettingPaths {
+		if strings.TrimSpace(os.Getenv(key)) != "" {
+			envPaths[settingPath] = true
+		}
+	}
+
+	// frontend settings edited by admins win over yaml and defaults
+	frontendFromDB := frontendsettings.HasOverride()
+	current.FrontendSettings = frontendsettings.Effective(current.FrontendSettings)
+
+	values := settingsValues(current)
+	for key, value := range values {
+		source := "default"
+		if frontendFromDB && strings.HasPrefix(key, "frontend_settings.") {
+			source = "db"
+		} else if envPaths[key] {
+			source = "env"
+		} else if yamlPaths[key] {
+			source = "yaml"
+		}
+		if secretSettingPaths[key] && value != "" {
+			value = redactedValue
+		}
+		response.Settings = append(response.Settings, effectiveSetting{
+			Path:       key,
+			Value:      value,
+			Source:     source,
+			Reloadable: isReloadablePath(key),
+		})
+	}
+	sort.Slice(response.Settings, func(i, j int) bool {
+		return response.Settings[i].Path < response.Settings[j].Path
+	})
+	return c.JSON(http.StatusOK, response)
+}
+
+// settingsValues flattens settings to dotted yaml paths
+func settingsValues(settings model.AppSettings) map[string]interface{} {
+	values := map[string]interface{}{}
+	encoded, err := yaml.Marshal(&settings)
+	if err != nil {
+		return values
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(encoded, &raw); err != nil {
+		return values
+	}
+	flattenSettings("", raw, func(key string, value interface{}) {
+		values[key] = value
+	})
+	return values
+}
+
+// RedactedSettings returns the effective settings as nested yaml values with secretSettingPaths redacted,
+// for printing them outside of the server
+func RedactedSettings() (map[string]interface{}, error) {
+	settings := Settings()
+	encoded, err := yaml.Marshal(&settings)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(encoded, &raw); err != nil {
+		return nil, err
+	}
+	redactSettings("", raw)
+	return raw, nil
+}
+
+func redactSettings(prefix string, node map[string]interface{}) {
+	for key, value := range node {
+		fullKey := key
+		if prefix != "" {
+			fullKey = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			redactSettings(fullKey, nested)
+			continue
+		}
+		if secretSettingPaths[fullKey] && value != "" {
+			node[key] = redactedValue
+		}
+	}
+}
+
+// flattenSettings walks nested yaml maps, lists and scalars are leaves
+func flattenSettings(prefix string, node map[string]interface{}, visit func(key string, value interface{})) {
+	for key, value := range node {
+		fullKey := key
+		if prefix != "" {
+			fullKey = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenSettings(fullKey, nested, visit)
+			continue
+		}
+		visit(fullKey, value)
+	}
+}
@@ -2,8 +2,19 @@ package evento
 
 import "net/http"
 
-import "github.com/labstack/echo/v4"
+import (
+	"github.com/eugenetolok/evento/internal/evento/frontendsettings"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/labstack/echo/v4"
+)
 
 func frontendConfig(c echo.Context) error {
-	return c.JSON(http.StatusOK, appSettings.FrontendSettings)
+	return c.JSON(http.StatusOK, frontendsettings.Effective(baseFrontendSettings()))
+}
+
+// baseFrontendSettings returns frontend settings from yaml and defaults, without db overrides
+func baseFrontendSettings() model.FrontendSettings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return appSettings.FrontendSettings
 }
@@ -0,0 +1,62 @@
+package frontendsettings
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"gorm.io/gorm"
+)
+
+var (
+	overrideMu        sync.RWMutex
+	override          *model.FrontendSettings
+	overrideUpdatedAt time.Time
+)
+
+// EnsureAndLoad migrates the override table and loads persisted settings into memory.
+func EnsureAndLoad(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.FrontendSettingsOverride{}); err != nil {
+		return err
+	}
+	var record model.FrontendSettingsOverride
+	if err := db.Order("updated_at desc").First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			setOverride(nil, time.Time{})
+			return nil
+		}
+		return err
+	}
+	var settings model.FrontendSettings
+	if err := json.Unmarshal([]byte(record.SettingsJSON), &settings); err != nil {
+		return err
+	}
+	setOverride(&settings, record.UpdatedAt)
+	return nil
+}
+
+// Effective returns persisted settings when present, base (yaml and defaults) otherwise.
+func Effective(base model.FrontendSettings) model.FrontendSettings {
+	overrideMu.RLock()
+	defer overrideMu.RUnlock()
+	if override == nil {
+		return base
+	}
+	return *override
+}
+
+// HasOverride reports whether settings were edited through the admin API.
+func HasOverride() bool {
+	overrideMu.RLock()
+	defer overrideMu.RUnlock()
+	return override != nil
+}
+
+func setOverride(settings *model.FrontendSettings, updatedAt time.Time) {
+	overrideMu.Lock()
+	override = settings
+	overrideUpdatedAt = updatedAt
+	overrideMu.Unlock()
+}
@@ -0,0 +1,25 @@
+package frontendsettings
+
+import (
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	echojwt "github.com/labstack/echo-jwt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// baseSettings returns frontend settings from yaml and defaults
+var baseSettings func() model.FrontendSettings
+
+// InitFrontendSettings wires admin endpoints for editable frontend settings.
+func InitFrontendSettings(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, base func() model.FrontendSettings) {
+	db = dbInstance
+	baseSettings = base
+	g.Use(echojwt.WithConfig(jwtConfig))
+
+	g.GET("", getFrontendSettings, utils.RoleMiddleware([]string{"admin"}))
+	g.PUT("", updateFrontendSettings, utils.RoleMiddleware([]string{"admin"}))
+	g.POST("/reset", resetFrontendSettings, utils.RoleMiddleware([]string{"admin"}))
+}
@@ -0,0 +1,187 @@
+package frontendsettings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// navRoles are keys allowed in nav_items, empty key is the menu for anonymous users
+var navRoles = map[string]bool{"": true, "admin": true, "editor": true, "company": true, "operator": true, "monitoring": true}
+
+var cityKeyPattern = regexp.MustCompile(`^[a-z0-9_-]{0,32}$`)
+var yearPattern = regexp.MustCompile(`^\d{4}$`)
+
+type frontendSettingsResponse struct {
+	Source    string                 `json:"source"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+	Settings  model.FrontendSettings `json:"settings"`
+}
+
+func getFrontendSettings(c echo.Context) error {
+	return c.JSON(http.StatusOK, currentResponse())
+}
+
+func updateFrontendSettings(c echo.Context) error {
+	// the body is bound onto the current settings so omitted fields keep their values,
+	// maps present in the body replace the current ones as a whole
+	current := currentResponse().Settings
+	settings := current
+	settings.Cities, settings.Docs, settings.NavItems = nil, nil, nil
+	if err := c.Bind(&settings); err != nil {
+		return c.String(http.StatusBadRequest, `{"error":"invalid request body"}`)
+	}
+	if settings.Cities == nil {
+		settings.Cities = copyStringMap(current.Cities)
+	}
+	if settings.Docs == nil {
+		settings.Docs = copyStringMap(current.Docs)
+	}
+	if settings.NavItems == nil {
+		settings.NavItems = make(map[string][]model.FrontendNavItem, len(current.NavItems))
+		for role, items := range current.NavItems {
+			settings.NavItems[role] = append([]model.FrontendNavItem{}, items...)
+		}
+	}
+	normalizeSettings(&settings)
+	if err := validateSettings(settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	userID, _ := utils.GetUser(c)
+	var record model.FrontendSettingsOverride
+	if err := db.Order("updated_at desc").First(&record).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+	record.SettingsJSON = string(encoded)
+	record.UpdatedByID = userID
+	if err := db.Save(&record).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	setOverride(&settings, record.UpdatedAt)
+	return c.JSON(http.StatusOK, currentResponse())
+}
+
+func resetFrontendSettings(c echo.Context) error {
+	if err := db.Unscoped().Where("1 = 1").Delete(&model.FrontendSettingsOverride{}).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	setOverride(nil, time.Time{})
+	return c.JSON(http.StatusOK, currentResponse())
+}
+
+func currentResponse() frontendSettingsResponse {
+	overrideMu.RLock()
+	defer overrideMu.RUnlock()
+	if override == nil {
+		return frontendSettingsResponse{Source: "config", Settings: baseSettings()}
+	}
+	updatedAt := overrideUpdatedAt
+	return frontendSettingsResponse{Source: "db", UpdatedAt: &updatedAt, Settings: *override}
+}
+
+func copyStringMap(source map[string]string) map[string]string {
+	result := make(map[string]string, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func normalizeSettings(settings *model.FrontendSettings) {
+	settings.Name = strings.TrimSpace(settings.Name)
+	settings.Year = strings.TrimSpace(settings.Year)
+	settings.Description = strings.TrimSpace(settings.Description)
+	settings.Version = strings.TrimSpace(settings.Version)
+	settings.City = strings.TrimSpace(settings.City)
+	settings.Links.Docs = strings.TrimSpace(settings.Links.Docs)
+	for key, items := range settings.NavItems {
+		for i := range items {
+			items[i].Label = strings.TrimSpace(items[i].Label)
+			items[i].Href = strings.TrimSpace(items[i].Href)
+		}
+		settings.NavItems[key] = items
+	}
+	for key, value := range settings.Docs {
+		settings.Docs[key] = strings.TrimSpace(value)
+	}
+	for key, value := range settings.Cities {
+		settings.Cities[key] = strings.TrimSpace(value)
+	}
+}
+
+func validateSettings(settings model.FrontendSettings) error {
+	if settings.Name == "" {
+		return errors.New("name is required")
+	}
+	if settings.Year != "" && !yearPattern.MatchString(settings.Year) {
+		return errors.New("year must contain 4 digits")
+	}
+	for key, name := range settings.Cities {
+		if !cityKeyPattern.MatchString(key) {
+			return fmt.Errorf("city key %q must contain only lowercase latin letters, digits, '-' or '_'", key)
+		}
+		if name == "" {
+			return fmt.Errorf("city %q has empty name", key)
+		}
+	}
+	for key, link := range settings.Docs {
+		if !cityKeyPattern.MatchString(key) {
+			return fmt.Errorf("docs key %q must contain only lowercase latin letters, digits, '-' or '_'", key)
+		}
+		if err := validateLink(link); err != nil {
+			return fmt.Errorf("docs %q: %w", key, err)
+		}
+	}
+	for role, items := range settings.NavItems {
+		if !navRoles[role] {
+			return fmt.Errorf("nav_items: unknown role %q", role)
+		}
+		for i, item := range items {
+			if item.Label == "" {
+				return fmt.Errorf("nav_items %q #%d: label is required", role, i+1)
+			}
+			if err := validateLink(item.Href); err != nil {
+				return fmt.Errorf("nav_items %q #%d: %w", role, i+1, err)
+			}
+		}
+	}
+	if settings.Links.Docs != "" {
+		if err := validateLink(settings.Links.Docs); err != nil {
+			return fmt.Errorf("links.docs: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateLink accepts app paths starting with "/" and absolute http(s) urls
+func validateLink(link string) error {
+	if link == "" {
+		return errors.New("link is required")
+	}
+	if strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//") {
+		return nil
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q must be a path starting with / or an http(s) url", link)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package evento
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// healthz is the liveness probe, the process is up and serving requests
+func healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// readyz is the readiness probe, checks database access and photo storage writability.
+// It is public, so the answer is only ok or fail per check and the errors go to the log
+func readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer cancel()
+
+	checks := map[string]error{
+		"database":      checkDatabase(ctx),
+		"photo_storage": checkPhotoStorage(),
+	}
+	response := healthResponse{Status: "ok", Checks: map[string]string{}}
+	status := http.StatusOK
+	for name, err := range checks {
+		response.Checks[name] = "ok"
+		if err != nil {
+			logger.FromEcho(c).Error("readiness check failed", "check", name, "error", err)
+			response.Checks[name] = "fail"
+			response.Status = "fail"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	return c.JSON(status, response)
+}
+
+func checkDatabase(ctx context.Context) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+	var result int
+	return db.WithContext(ctx).Raw("SELECT 1").Scan(&result).Error
+}
+
+func checkPhotoStorage() error {
+	file, err := os.CreateTemp(photoStorageDir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	file.Close()
+	return os.Remove(name)
+}
+
+// metricsAuth requires "Authorization: Bearer <metrics_token>", without a configured token metrics are off
+func metricsAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		settingsMu.RLock()
+		token := appSettings.SiteSettings.MetricsToken
+		settingsMu.RUnlock()
+		if token == "" {
+			return c.String(http.StatusNotFound, `{"error":"metrics are disabled, set site_settings.metrics_token"}`)
+		}
+		provided := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.String(http.StatusUnauthorized, `{"error":"invalid metrics token"}`)
+		}
+		return next(c)
+	}
+}
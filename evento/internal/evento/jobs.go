@@ -0,0 +1,73 @@
+package evento
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/eugenetolok/evento/internal/evento/company"
+	"github.com/eugenetolok/evento/internal/evento/scheduledaction"
+	"github.com/eugenetolok/evento/pkg/jobs"
+	"github.com/labstack/echo/v4"
+)
+
+var jobRunner = jobs.NewRunner()
+
+// registerJobs adds background jobs to the runner, they start with StartJobs
+func registerJobs() error {
+	if err := scheduledaction.FailInterrupted(db); err != nil {
+		slog.Error("unable to fail interrupted scheduled actions", "error", err)
+	}
+	for _, job := range []jobs.Job{
+		company.FreezeSchedulerJob(db),
+		scheduledaction.SchedulerJob(db),
+	} {
+		if err := jobRunner.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartJobs starts background jobs, they are stopped when ctx is done or by StopJobs
+func StartJobs(ctx context.Context) {
+	jobRunner.Start(ctx)
+}
+
+// StopJobs cancels background jobs and waits for running ones until ctx expires
+func StopJobs(ctx context.Context) error {
+	return jobRunner.Stop(ctx)
+}
+
+// listJobs returns status of all background jobs
+func listJobs(c echo.Context) error {
+	return c.JSON(http.StatusOK, jobRunner.Statuses())
+}
+
+// getJob returns status and run history of a job
+func getJob(c echo.Context) error {
+	status, err := jobRunner.Status(c.Param("name"))
+	if err != nil {
+		return c.String(http.StatusNotFound, `Задача не найдена`)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// runJob triggers a job immediately, the run goes on in background
+func runJob(c echo.Context) error {
+	name := c.Param("name")
+	err := jobRunner.Trigger(name)
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return c.String(http.StatusNotFound, `Задача не найдена`)
+	case errors.Is(err, jobs.ErrRunning):
+		return c.String(http.StatusConflict, `Задача уже выполняется`)
+	case errors.Is(err, jobs.ErrStopped):
+		return c.String(http.StatusServiceUnavailable, `Фоновые задачи остановлены`)
+	case err != nil:
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	status, _ := jobRunner.Status(name)
+	return c.JSON(http.StatusAccepted, status)
+}
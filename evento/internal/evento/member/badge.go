@@ -3,10 +3,10 @@ package member
 import (
 	"bytes"
 	"errors"
-	"fmt"
 	"net/http"
 
 	"github.com/eugenetolok/evento/internal/evento/badge"
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -18,16 +18,16 @@ func getBadgePayload(c echo.Context) error {
 	id, _ := uuid.Parse(c.Param("id"))
 
 	var member model.Member
-	if err := db.Preload("Accreditation.Gates").Preload("Gates").First(&member, id).Error; err != nil {
+	if err := badge.PreloadTemplateData(db).First(&member, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.String(http.StatusNotFound, "Member not found")
 		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	var badgeTemplate model.BadgeTemplate
-	if err := db.Where("is_default = ?", true).First(&badgeTemplate).Error; err != nil {
-		return c.String(http.StatusInternalServerError, "Default badge template not found")
+	badgeTemplate, err := badge.NewTemplateResolver(db).ForMember(member)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Badge template not found")
 	}
 
 	payload, err := badge.ProcessBadgeTemplate(member, badgeTemplate.TemplateJSON)
@@ -46,22 +46,23 @@ func getMassBadgePayloads(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Invalid request body")
 	}
 
-	var badgeTemplate model.BadgeTemplate
-	if err := db.Where("is_default = ?", true).First(&badgeTemplate).Error; err != nil {
-		return c.String(http.StatusInternalServerError, "Default badge template not found")
-	}
-
 	var members []model.Member
-	if err := db.Preload("Accreditation.Gates").Preload("Gates").Where("id IN ?", body.MemberIDs).Find(&members).Error; err != nil {
+	if err := badge.PreloadTemplateData(db).Where("id IN ?", body.MemberIDs).Find(&members).Error; err != nil {
 		return c.String(http.StatusInternalServerError, "Failed to fetch members")
 	}
 
+	resolver := badge.NewTemplateResolver(db)
 	var payloads [][]byte
 	for _, member := range members {
+		badgeTemplate, err := resolver.ForMember(member)
+		if err != nil {
+			logger.FromEcho(c).Warn("badge template not found", "member_id", member.ID, "error", err)
+			continue
+		}
 		payload, err := badge.ProcessBadgeTemplate(member, badgeTemplate.TemplateJSON)
 		if err != nil {
 			// Log the error and skip this member
-			fmt.Printf("Error processing template for member %s: %v\n", member.ID, err)
+			logger.FromEcho(c).Warn("badge template processing failed", "member_id", member.ID, "error", err)
 			continue
 		}
 		payloads = append(payloads, payload)
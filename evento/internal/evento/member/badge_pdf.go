@@ -0,0 +1,114 @@
+package member
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/eugenetolok/evento/internal/evento/badge"
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// maxBadgesPerPDF limits one mass pdf, larger batches are split by the client
+const maxBadgesPerPDF = 500
+
+// getBadgePDF renders the badge of the member, page layout comes from query params
+func getBadgePDF(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var layout badge.PDFLayout
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &layout); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	var member model.Member
+	if err := badge.PreloadTemplateData(db).First(&member, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusNotFound, `Участник не найден`)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	item, err := pdfBadge(badge.NewTemplateResolver(db), member)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Не удалось обработать шаблон бейджа: "+err.Error())
+	}
+	return renderBadgesPDF(c, []badge.PDFBadge{item}, layout, "badge-"+member.ID.String()+".pdf")
+}
+
+// getMassBadgesPDF renders badges of members in the requested order on sheets of the layout
+func getMassBadgesPDF(c echo.Context) error {
+	var body struct {
+		MemberIDs []uuid.UUID     `json:"memberIds"`
+		Layout    badge.PDFLayout `json:"layout"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.String(http.StatusBadRequest, "Неверный запрос")
+	}
+	if len(body.MemberIDs) == 0 {
+		return c.String(http.StatusBadRequest, "Не выбраны участники")
+	}
+	if len(body.MemberIDs) > maxBadgesPerPDF {
+		return c.String(http.StatusBadRequest, fmt.Sprintf("В одном pdf не больше %d бейджей", maxBadgesPerPDF))
+	}
+	var members []model.Member
+	if err := badge.PreloadTemplateData(db).Where("id IN ?", body.MemberIDs).Find(&members).Error; err != nil {
+		return c.String(http.StatusInternalServerError, "Не удалось загрузить участников")
+	}
+	byID := make(map[uuid.UUID]model.Member, len(members))
+	for _, member := range members {
+		byID[member.ID] = member
+	}
+	resolver := badge.NewTemplateResolver(db)
+	items := make([]badge.PDFBadge, 0, len(members))
+	for _, id := range body.MemberIDs {
+		member, ok := byID[id]
+		if !ok {
+			continue
+		}
+		item, err := pdfBadge(resolver, member)
+		if err != nil {
+			logger.FromEcho(c).Warn("badge template processing failed", "member_id", member.ID, "error", err)
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return c.String(http.StatusNotFound, "Нет бейджей для печати")
+	}
+	return renderBadgesPDF(c, items, body.Layout, "badges.pdf")
+}
+
+// pdfBadge renders the badge payload of the member with the template resolved for it
+func pdfBadge(resolver *badge.TemplateResolver, member model.Member) (badge.PDFBadge, error) {
+	badgeTemplate, err := resolver.ForMember(member)
+	if err != nil {
+		return badge.PDFBadge{}, err
+	}
+	payload, err := badge.ProcessBadgeTemplate(member, badgeTemplate.TemplateJSON)
+	if err != nil {
+		return badge.PDFBadge{}, err
+	}
+	item := badge.PDFBadge{Payload: payload}
+	if member.PhotoFilename != "" {
+		item.Photo = filepath.Join(memberPhotoDir, member.PhotoFilename)
+	}
+	return item, nil
+}
+
+func renderBadgesPDF(c echo.Context, items []badge.PDFBadge, layout badge.PDFLayout, filename string) error {
+	settings := currentBadgeSettings()
+	layout, err := badge.ResolvePDFLayout(layout, settings)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	var buf bytes.Buffer
+	if err := badge.RenderPDF(&buf, items, layout, settings); err != nil {
+		return c.String(http.StatusInternalServerError, "Не удалось сформировать бейджи: "+err.Error())
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="`+filename+`"`)
+	return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
@@ -2,9 +2,9 @@ package member
 
 import (
 	"errors"
-	"log"
 	"net/http"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -27,13 +27,19 @@ func regenerateBarcode(c echo.Context) error {
 
 	newBarcode, err := generateUniqueMemberBarcode(db)
 	if err != nil {
-		log.Printf("Failed to generate new barcode: %v", err)
+		logger.FromEcho(c).Error("failed to generate new barcode", "member_id", member.ID, "error", err)
 		return c.String(http.StatusInternalServerError, "Failed to generate barcode")
 	}
-	member.Barcode = newBarcode
 
-	if err := db.Save(&member).Error; err != nil {
-		log.Printf("Failed to save member with new barcode: %v", err)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := replaceMemberBarcode(tx, member, newBarcode, barcodeRevokedRegenerated); err != nil {
+			return err
+		}
+		member.Barcode = newBarcode
+		return tx.Save(&member).Error
+	})
+	if err != nil {
+		logger.FromEcho(c).Error("failed to save member with new barcode", "member_id", member.ID, "error", err)
 		return c.String(http.StatusInternalServerError, "Failed to update barcode")
 	}
 
@@ -25,11 +25,15 @@ func generateUniqueMemberBarcode(tx *gorm.DB) (string, error) {
 			continue
 		}
 
-		var count int64
+		// revoked barcodes are never issued again
+		var count, issued int64
 		if err := tx.Model(&model.Member{}).Where("barcode = ?", candidate).Count(&count).Error; err != nil {
 			return "", err
 		}
-		if count == 0 {
+		if err := tx.Model(&model.MemberBarcode{}).Where("barcode = ?", candidate).Count(&issued).Error; err != nil {
+			return "", err
+		}
+		if count == 0 && issued == 0 {
 			return candidate, nil
 		}
 	}
@@ -0,0 +1,125 @@
+package member
+
+import (
+	"net/http"
+	"time"
+
+	codec "github.com/eugenetolok/evento/pkg/credential"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Reasons of barcode revocation
+const (
+	barcodeRevokedLost        = "lost"
+	barcodeRevokedRegenerated = "regenerated"
+)
+
+// RevokedBarcode is a replaced barcode in the offline feed, scanners show when the current badge was issued.
+// Fingerprint is the signed credential fingerprint of the barcode, credentials carrying it are revoked too
+type RevokedBarcode struct {
+	Hash            string     `json:"hash"`
+	Fingerprint     string     `json:"fingerprint"`
+	MemberID        uuid.UUID  `json:"member_id"`
+	RevokedAt       time.Time  `json:"revoked_at"`
+	Reason          string     `json:"reason"`
+	CurrentIssuedAt *time.Time `json:"current_issued_at"`
+}
+
+// recordIssuedBarcode adds the current barcode of a member to the history
+func recordIssuedBarcode(tx *gorm.DB, memberID uuid.UUID, barcode string) error {
+	if barcode == "" {
+		return nil
+	}
+	return tx.Create(&model.MemberBarcode{MemberID: memberID, Barcode: barcode, IssuedAt: time.Now()}).Error
+}
+
+// replaceMemberBarcode revokes the current barcode of the member and records the new one,
+// barcodes issued before the history existed are recorded as revoked
+func replaceMemberBarcode(tx *gorm.DB, member model.Member, barcode, reason string) error {
+	now := time.Now()
+	if member.Barcode != "" {
+		result := tx.Model(&model.MemberBarcode{}).
+			Where("member_id = ? AND barcode = ? AND revoked_at IS NULL", member.ID, member.Barcode).
+			Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			revoked := model.MemberBarcode{MemberID: member.ID, Barcode: member.Barcode, IssuedAt: member.CreatedAt, RevokedAt: &now, RevokeReason: reason}
+			if err := tx.Create(&revoked).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return recordIssuedBarcode(tx, member.ID, barcode)
+}
+
+// findRevokedBarcode looks up a scanned code among revoked barcodes with the issue time of the current one
+func findRevokedBarcode(hash string) (RevokedBarcode, bool) {
+	var revoked model.MemberBarcode
+	if hash == "" || db.Where("barcode = ? AND revoked_at IS NOT NULL", hash).Order("revoked_at DESC").First(&revoked).Error != nil {
+		return RevokedBarcode{}, false
+	}
+	feed := revokedBarcodeFeed([]model.MemberBarcode{revoked})
+	return feed[0], true
+}
+
+// findRevokedFingerprint looks up the revoked barcode of the member a signed credential was issued for
+func findRevokedFingerprint(memberID uuid.UUID, fingerprint string) (RevokedBarcode, bool) {
+	var revoked []model.MemberBarcode
+	if db.Where("member_id = ? AND revoked_at IS NOT NULL", memberID).Order("revoked_at DESC").Find(&revoked).Error != nil {
+		return RevokedBarcode{}, false
+	}
+	for _, barcode := range revoked {
+		if codec.Fingerprint(barcode.Barcode) == fingerprint {
+			return revokedBarcodeFeed([]model.MemberBarcode{barcode})[0], true
+		}
+	}
+	return RevokedBarcode{}, false
+}
+
+// revokedBarcodes is the revocation list of the offline feed
+func revokedBarcodes() ([]RevokedBarcode, error) {
+	var revoked []model.MemberBarcode
+	if err := db.Where("revoked_at IS NOT NULL").Order("revoked_at").Find(&revoked).Error; err != nil {
+		return nil, err
+	}
+	return revokedBarcodeFeed(revoked), nil
+}
+
+func revokedBarcodeFeed(revoked []model.MemberBarcode) []RevokedBarcode {
+	memberIDs := make([]uuid.UUID, 0, len(revoked))
+	for _, barcode := range revoked {
+		memberIDs = append(memberIDs, barcode.MemberID)
+	}
+	var current []model.MemberBarcode
+	if len(memberIDs) > 0 {
+		db.Where("member_id IN ? AND revoked_at IS NULL", memberIDs).Find(&current)
+	}
+	issuedAt := map[uuid.UUID]time.Time{}
+	for _, barcode := range current {
+		issuedAt[barcode.MemberID] = barcode.IssuedAt
+	}
+	feed := make([]RevokedBarcode, 0, len(revoked))
+	for _, barcode := range revoked {
+		item := RevokedBarcode{Hash: barcode.Barcode, Fingerprint: codec.Fingerprint(barcode.Barcode), MemberID: barcode.MemberID, RevokedAt: *barcode.RevokedAt, Reason: barcode.RevokeReason}
+		if issued, ok := issuedAt[barcode.MemberID]; ok {
+			item.CurrentIssuedAt = &issued
+		}
+		feed = append(feed, item)
+	}
+	return feed
+}
+
+// getMemberBarcodes returns every barcode issued to the member, the newest first
+func getMemberBarcodes(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var barcodes []model.MemberBarcode
+	if err := db.Where("member_id = ?", id).Order("issued_at DESC").Find(&barcodes).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, barcodes)
+}
@@ -0,0 +1,179 @@
+package member
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/internal/evento/badge"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Barcode image sizes in pixels
+const (
+	barcodeImageDefaultSize = 300
+	barcodeImageMinSize     = 32
+	barcodeImageMaxSize     = 2000
+)
+
+var barcodeFormats = map[string]bool{"qr": true, "code128": true, "datamatrix": true}
+
+// barcodeURLTTL is the lifetime of signed barcode urls, they are put into emails
+const barcodeURLTTL = 30 * 24 * time.Hour
+
+// publicURLKey signs public barcode and photo urls, derived from the jwt signing key
+var publicURLKey []byte
+
+type barcodeImageOptions struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// getBarcodeImage renders the member barcode for users who can see the member
+func getBarcodeImage(imageType string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, _ := uuid.Parse(c.Param("id"))
+		var member model.Member
+		if err := db.Select("id", "company_id", "barcode").First(&member, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.String(http.StatusNotFound, `Участник не найден`)
+			}
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		var company model.Company
+		if err := db.Preload("User").First(&company, member.CompanyID).Error; err != nil {
+			return c.String(http.StatusNotFound, `ID компании неверный`)
+		}
+		if !utils.CheckCompanyGetPermission(c, company) {
+			return c.String(http.StatusNotFound, `У вас недостаточно прав, чтобы сделать данный запрос`)
+		}
+		return writeBarcodeImage(c, member, imageType)
+	}
+}
+
+// getPublicBarcodeImage renders the member barcode for signed urls from emails and public pages,
+// the signature is bound to the barcode so a replaced barcode invalidates old urls
+func getPublicBarcodeImage(imageType string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.String(http.StatusNotFound, `Участник не найден`)
+		}
+		expires := c.QueryParam("exp")
+		expiresAt, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || time.Now().Unix() > expiresAt {
+			return c.String(http.StatusNotFound, `Участник не найден`)
+		}
+		var member model.Member
+		if err := db.Select("id", "barcode", "blocked").First(&member, id).Error; err != nil {
+			return c.String(http.StatusNotFound, `Участник не найден`)
+		}
+		if member.Blocked || !hmac.Equal([]byte(c.QueryParam("sig")), []byte(barcodeSignature(member, expires))) {
+			return c.String(http.StatusNotFound, `Участник не найден`)
+		}
+		c.Response().Header().Set("Cache-Control", "private, max-age=3600")
+		return writeBarcodeImage(c, member, imageType)
+	}
+}
+
+// getBarcodeURLs returns signed public urls of the member barcode images
+func getBarcodeURLs(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var member model.Member
+	if err := db.Select("id", "company_id", "barcode").First(&member, id).Error; err != nil {
+		return c.String(http.StatusNotFound, `Участник не найден`)
+	}
+	var company model.Company
+	if err := db.Preload("User").First(&company, member.CompanyID).Error; err != nil {
+		return c.String(http.StatusNotFound, `ID компании неверный`)
+	}
+	if !utils.CheckCompanyGetPermission(c, company) {
+		return c.String(http.StatusNotFound, `У вас недостаточно прав, чтобы сделать данный запрос`)
+	}
+	format := c.QueryParam("format")
+	return c.JSON(http.StatusOK, map[string]string{
+		"png": PublicBarcodeURL(member, "png", format),
+		"svg": PublicBarcodeURL(member, "svg", format),
+	})
+}
+
+// PublicBarcodeURL is the path of the signed public barcode image of the member, valid for barcodeURLTTL
+func PublicBarcodeURL(member model.Member, imageType, format string) string {
+	expires := strconv.FormatInt(time.Now().Add(barcodeURLTTL).Unix(), 10)
+	query := url.Values{"exp": {expires}, "sig": {barcodeSignature(member, expires)}}
+	if format != "" {
+		query.Set("format", format)
+	}
+	return "/api/public/members/" + member.ID.String() + "/barcode." + imageType + "?" + query.Encode()
+}
+
+func barcodeSignature(member model.Member, expires string) string {
+	mac := hmac.New(sha256.New, publicURLKey)
+	mac.Write([]byte("barcode:" + member.ID.String() + ":" + member.Barcode + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func writeBarcodeImage(c echo.Context, member model.Member, imageType string) error {
+	if member.Barcode == "" {
+		return c.String(http.StatusNotFound, `У участника нет штрихкода`)
+	}
+	options, err := parseBarcodeImageOptions(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if imageType == "svg" {
+		image, err := badge.BarcodeSVG(options.Format, member.Barcode, options.Width, options.Height)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		return c.Blob(http.StatusOK, "image/svg+xml", image)
+	}
+	image, err := badge.BarcodePNG(options.Format, member.Barcode, options.Width, options.Height)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.Blob(http.StatusOK, "image/png", image)
+}
+
+// parseBarcodeImageOptions reads ?format=qr|code128|datamatrix&size=&height=, code128 is three times wider than high by default
+func parseBarcodeImageOptions(c echo.Context) (barcodeImageOptions, error) {
+	options := barcodeImageOptions{Format: strings.ToLower(c.QueryParam("format")), Width: barcodeImageDefaultSize}
+	if options.Format == "" {
+		options.Format = "qr"
+	}
+	if !barcodeFormats[options.Format] {
+		return options, errors.New("Формат штрихкода: qr, code128 или datamatrix")
+	}
+	var err error
+	if raw := c.QueryParam("size"); raw != "" {
+		if options.Width, err = strconv.Atoi(raw); err != nil {
+			return options, errors.New("Неверный размер")
+		}
+	}
+	options.Height = options.Width
+	if options.Format == "code128" && options.Width/3 >= barcodeImageMinSize {
+		options.Height = options.Width / 3
+	}
+	if raw := c.QueryParam("height"); raw != "" {
+		if options.Height, err = strconv.Atoi(raw); err != nil {
+			return options, errors.New("Неверный размер")
+		}
+	}
+	for _, size := range []int{options.Width, options.Height} {
+		if size < barcodeImageMinSize || size > barcodeImageMaxSize {
+			return options, errors.New("Размер должен быть от " + strconv.Itoa(barcodeImageMinSize) + " до " + strconv.Itoa(barcodeImageMaxSize))
+		}
+	}
+	return options, nil
+}
@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/eugenetolok/evento/internal/evento/credential"
+	codec "github.com/eugenetolok/evento/pkg/credential"
+	"github.com/eugenetolok/evento/pkg/metrics"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -14,8 +17,9 @@ import (
 
 // SuperCheckAnswer ...
 type SuperCheckAnswer struct {
-	Gates  []model.Gate  `json:"gates"`
-	Checks []CheckAnswer `json:"checks"`
+	Gates   []model.Gate     `json:"gates"`
+	Checks  []CheckAnswer    `json:"checks"`
+	Revoked []RevokedBarcode `json:"revoked"`
 }
 
 // CheckAnswer ...
@@ -30,6 +34,17 @@ type CheckAnswer struct {
 	FIO             string      `json:"fio"`
 	ID              uuid.UUID   `json:"id"`
 	AccreditationID uuid.UUID   `json:"accreditation_id"`
+	GateClosed      bool        `json:"gate_closed"`
+	// PhotoRequired asks the guard to compare the member with PhotoURL, a signed url valid for a few minutes
+	PhotoRequired bool   `json:"photo_required"`
+	PhotoURL      string `json:"photo_url,omitempty"`
+	// PhotoMissing is set in the offline feed for members without a photo, gates with require_photo deny them
+	PhotoMissing bool `json:"photo_missing,omitempty"`
+	// Credentials are nfc cards, bangles and extra barcodes of the member, offline scanners accept them like the barcode
+	Credentials []OfflineCredential `json:"credentials,omitempty"`
+	// Revoked is set for replaced barcodes, Message tells when the current badge was issued
+	Revoked bool   `json:"revoked"`
+	Message string `json:"message,omitempty"`
 }
 
 // CheckInput ...
@@ -52,19 +67,72 @@ func check(c echo.Context) error {
 	var checkAnswer CheckAnswer
 	var checkInput CheckInput
 	if err := c.Bind(&checkInput); err != nil {
+		metrics.ObserveGateScan("unknown", "invalid_input")
 		return c.JSON(http.StatusOK, checkAnswer)
 	}
+	scanGate := findScanGate(checkInput.GateID)
+	gateLabel := scanGateLabel(scanGate)
 	var member model.Member
-	if err := db.Preload("Accreditation.Gates").Preload("Gates").Preload("Events").Where("barcode = ?", checkInput.Hash).First(&member).Error; err != nil {
+	query := db.Preload("Accreditation.Gates").Preload("Gates").Preload("Events")
+	if codec.IsCredential(checkInput.Hash) {
+		claims, err := credential.Verify(checkInput.Hash)
+		if errors.Is(err, credential.ErrBarcodeReplaced) {
+			metrics.ObserveGateScan(gateLabel, "revoked")
+			if revoked, ok := findRevokedFingerprint(claims.MemberID, claims.Fingerprint); ok {
+				return c.JSON(http.StatusOK, revokedCheckAnswer(revoked))
+			}
+			return c.JSON(http.StatusOK, CheckAnswer{Revoked: true, ID: claims.MemberID, Message: "Пропуск выпущен для замененного штрихкода"})
+		}
+		if err != nil {
+			metrics.ObserveGateScan(gateLabel, "invalid_credential")
+			return c.JSON(http.StatusNotFound, checkAnswer)
+		}
+		query = query.Where("id = ?", claims.MemberID)
+	} else if memberCredential, ok := findMemberCredential(checkInput.Hash); ok {
+		if message := memberCredentialDenial(memberCredential, time.Now()); message != "" {
+			metrics.ObserveGateScan(gateLabel, "credential_"+memberCredential.Status)
+			checkAnswer = CheckAnswer{Hash: checkInput.Hash, ID: memberCredential.MemberID, Message: message}
+			checkAnswer.Revoked = memberCredential.Status == model.CredentialRevoked
+			return c.JSON(http.StatusOK, checkAnswer)
+		}
+		query = query.Where("id = ?", memberCredential.MemberID)
+	} else {
+		query = query.Where("barcode = ?", checkInput.Hash)
+	}
+	if err := query.First(&member).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if revoked, ok := findRevokedBarcode(checkInput.Hash); ok {
+				metrics.ObserveGateScan(gateLabel, "revoked")
+				return c.JSON(http.StatusOK, revokedCheckAnswer(revoked))
+			}
+			metrics.ObserveGateScan(gateLabel, "not_found")
 			return c.JSON(http.StatusNotFound, checkAnswer)
 		}
 	}
 
 	checkAnswer.Success = true
 
+	photoDenied := false
 	if member.Blocked {
 		checkAnswer.Success = false
+		metrics.ObserveGateScan(gateLabel, "blocked")
+	} else if scanGate.Closed {
+		checkAnswer.Success = false
+		checkAnswer.GateClosed = true
+		metrics.ObserveGateScan(gateLabel, "gate_closed")
+	} else if photoRequired(member, scanGate) && member.PhotoFilename == "" {
+		checkAnswer.Success = false
+		checkAnswer.Message = "Нужна сверка с фото, у участника нет фото"
+		photoDenied = true
+		metrics.ObserveGateScan(gateLabel, "no_photo")
+	} else {
+		metrics.ObserveGateScan(gateLabel, "allowed")
+	}
+	if photoRequired(member, scanGate) {
+		checkAnswer.PhotoRequired = true
+		if member.PhotoFilename != "" {
+			checkAnswer.PhotoURL = signedPhotoURL(member, time.Now())
+		}
 	}
 	checkAnswer.Inside = member.InZone
 	checkAnswer.Hash = member.Barcode
@@ -93,6 +161,10 @@ func check(c echo.Context) error {
 			checkAnswer.Events = append(checkAnswer.Events, event.ID)
 		}
 	}
+	// a scan at a closed gate or without the required photo is not an entry
+	if checkAnswer.GateClosed || photoDenied {
+		return c.JSON(http.StatusOK, checkAnswer)
+	}
 	member.InZone = true
 	db.Save(&member)
 	var memberPass model.MemberPass
@@ -103,7 +175,41 @@ func check(c echo.Context) error {
 	return c.JSON(http.StatusOK, checkAnswer)
 }
 
-// offlineScanner ...
+// revokedCheckAnswer denies a replaced barcode and names the member so the badge can be taken away
+func revokedCheckAnswer(revoked RevokedBarcode) CheckAnswer {
+	checkAnswer := CheckAnswer{Revoked: true, Hash: revoked.Hash, ID: revoked.MemberID}
+	var member model.Member
+	if db.Select("id", "surname", "name", "middlename", "accreditation_id").First(&member, revoked.MemberID).Error == nil {
+		checkAnswer.FIO = fmt.Sprintf("%s %s %s", member.Surname, member.Name, member.Middlename)
+		checkAnswer.AccreditationID = member.AccreditationID
+	}
+	checkAnswer.Message = "Штрихкод отозван " + revoked.RevokedAt.Format("02.01.2006 15:04")
+	if revoked.CurrentIssuedAt != nil {
+		checkAnswer.Message += ", действующий бейдж выдан " + revoked.CurrentIssuedAt.Format("02.01.2006 15:04")
+	}
+	return checkAnswer
+}
+
+// findScanGate loads the gate of a scan, empty gate when the id is unknown
+func findScanGate(rawGateID string) model.Gate {
+	var gate model.Gate
+	gateID, err := uuid.Parse(rawGateID)
+	if err != nil {
+		return gate
+	}
+	db.Select("id", "name", "closed", "require_photo").First(&gate, gateID)
+	return gate
+}
+
+// scanGateLabel resolves gate name for metrics, unknown ids share one label to keep cardinality low
+func scanGateLabel(gate model.Gate) string {
+	if gate.ID == uuid.Nil {
+		return "unknown"
+	}
+	return gate.Name
+}
+
+// offlineScanner returns every member for scanners working without network, thumbnails are paged by offlinePhotos
 func offlineScanner(c echo.Context) error {
 	var checkAnswers []CheckAnswer
 	var members []model.Member
@@ -121,9 +227,21 @@ func offlineScanner(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, SuperCheckAnswer{Checks: checkAnswers, Gates: gates})
 	}
 
+	credentials, err := offlineMemberCredentials()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SuperCheckAnswer{Checks: checkAnswers, Gates: gates})
+	}
+
 	for _, member := range members {
 		var checkAnswer CheckAnswer
+		checkAnswer.Credentials = credentials[member.ID]
+		checkAnswer.PhotoRequired = member.Accreditation.RequirePhoto
+		checkAnswer.PhotoMissing = member.PhotoFilename == ""
 		checkAnswer.Success = true
+		if checkAnswer.PhotoRequired && checkAnswer.PhotoMissing {
+			checkAnswer.Success = false
+			checkAnswer.Message = "Нужна сверка с фото, у участника нет фото"
+		}
 		checkAnswer.Inside = member.InZone
 		checkAnswer.Hash = member.Barcode
 		checkAnswer.AccreditationID = member.AccreditationID
@@ -153,7 +271,11 @@ func offlineScanner(c echo.Context) error {
 		}
 		checkAnswers = append(checkAnswers, checkAnswer)
 	}
-	return c.JSON(http.StatusOK, SuperCheckAnswer{Checks: checkAnswers, Gates: gates})
+	revoked, err := revokedBarcodes()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, SuperCheckAnswer{Checks: checkAnswers, Gates: gates})
+	}
+	return c.JSON(http.StatusOK, SuperCheckAnswer{Checks: checkAnswers, Gates: gates, Revoked: revoked})
 }
 
 type MemberPassResponse struct {
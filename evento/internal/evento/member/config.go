@@ -1,6 +1,11 @@
 package member
 
 import (
+	"log/slog"
+	"sync"
+
+	"github.com/eugenetolok/evento/pkg/imaging"
+	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	echojwt "github.com/labstack/echo-jwt"
 	"github.com/labstack/echo/v4"
@@ -9,11 +14,44 @@ import (
 
 var db *gorm.DB
 var memberPhotoDir string
+var badgeSettings model.BadgeSettings
+var badgeSettingsMu sync.RWMutex
+var photoOptions imaging.Options
+var photoFaceDetector imaging.FaceDetector
+
+// SetBadgeSettings replaces pdf layout and font settings, used on config reload
+func SetBadgeSettings(settings model.BadgeSettings) {
+	settings.FontsDir = utils.ResolvePath(settings.FontsDir)
+	badgeSettingsMu.Lock()
+	badgeSettings = settings
+	badgeSettingsMu.Unlock()
+}
+
+func currentBadgeSettings() model.BadgeSettings {
+	badgeSettingsMu.RLock()
+	defer badgeSettingsMu.RUnlock()
+	return badgeSettings
+}
 
 // InitMembers entry point of members
-func InitMembers(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, photoDir string) {
+func InitMembers(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, photoDir string, badgeSettingsIn model.BadgeSettings, photoSettings model.PhotoSettings) {
 	db = dbInstance
 	memberPhotoDir = photoDir
+	SetBadgeSettings(badgeSettingsIn)
+	photoOptions = imaging.Options{
+		AspectWidth:   photoSettings.AspectWidth,
+		AspectHeight:  photoSettings.AspectHeight,
+		MaxWidth:      photoSettings.MaxWidth,
+		Quality:       photoSettings.Quality,
+		ThumbnailSize: photoSettings.ThumbnailSize,
+	}
+	var err error
+	if photoFaceDetector, err = imaging.NewFaceDetector(photoSettings.FaceCheck); err != nil {
+		slog.Error("photo face check is disabled", "error", err)
+	}
+	signingKey, _ := jwtConfig.SigningKey.([]byte)
+	publicURLKey = utils.DeriveKey(signingKey, "public-url")
+	printOverrideKey = utils.DeriveKey(signingKey, "print-override")
 	// to here
 	g.Use(echojwt.WithConfig(jwtConfig))
 	// kick start
@@ -24,6 +62,7 @@ func InitMembers(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, p
 	g.GET("/images", images, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.POST("/check", check, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.GET("/offline", offlineScanner, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.GET("/offline/photos", offlinePhotos, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.GET("/:id/memberPasses", memberPasses, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.GET("/search", searchMembers, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.GET("/smart-management", getSmartManagementData, utils.RoleMiddleware([]string{"admin"}))
@@ -33,19 +72,43 @@ func InitMembers(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, p
 	g.GET("/:id", getMember, utils.UUIDMiddleware)
 	g.PUT("/:id", updateMember, utils.UUIDMiddleware) // disable operator to be able to put on everything?
 	g.POST("/:id/regenerate-barcode", regenerateBarcode, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.GET("/:id/barcodes", getMemberBarcodes, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.GET("/:id/credentials", getMemberCredentials, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.POST("/:id/credentials", bindMemberCredential, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.PUT("/:id/credentials/:credentialId", updateMemberCredential, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.DELETE("/:id/credentials/:credentialId", unbindMemberCredential, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.DELETE("/:id", deleteMember, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.GET("/:id/photo", serveMemberPhoto, utils.UUIDMiddleware)
 	g.POST("/:id/photo", uploadMemberPhoto, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
+	g.POST("/photos-zip", uploadMemberPhotosZip, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.POST("/import", importMembers, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.GET("/template", generateTemplate, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
+	g.GET("/registration-windows", getRegistrationWindows, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.GET("/company", getCompanyMembers, utils.RoleMiddleware([]string{"admin", "editor", "company"}))
 	g.GET("/editor", getEditorMembers, utils.RoleMiddleware([]string{"admin", "editor"}))
 	// state:
 	g.POST("/setstate/:id", setState, utils.RoleMiddleware([]string{"admin"}))
 	g.POST("/print/:id", print, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.POST("/massPrint", massPrint, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.POST("/print-override", issuePrintOverride, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/print-jobs", getPrintJobs, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/print-jobs/:id", getPrintJob, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/print-report", getPrintReport, utils.RoleMiddleware([]string{"admin", "editor"}))
 	g.POST("/giveBangle/:id", giveBangle, utils.RoleMiddleware([]string{"admin", "operator"}))
 	// badge:
 	g.GET("/:id/badge-payload", getBadgePayload, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
 	g.POST("/badge-payloads-mass", getMassBadgePayloads, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.GET("/:id/badge.pdf", getBadgePDF, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.POST("/badges-pdf", getMassBadgesPDF, utils.RoleMiddleware([]string{"admin", "operator"}))
+	g.GET("/:id/barcode.png", getBarcodeImage("png"), utils.UUIDMiddleware)
+	g.GET("/:id/barcode.svg", getBarcodeImage("svg"), utils.UUIDMiddleware)
+	g.GET("/:id/barcode-urls", getBarcodeURLs, utils.UUIDMiddleware)
+	g.GET("/:id/credential", getMemberCredential, utils.UUIDMiddleware)
+}
+
+// InitPublicMembers serves signed member urls without authentication
+func InitPublicMembers(g *echo.Group) {
+	g.GET("/:id/barcode.png", getPublicBarcodeImage("png"))
+	g.GET("/:id/barcode.svg", getPublicBarcodeImage("svg"))
+	g.GET("/:id/photo", getPublicPhoto)
 }
@@ -0,0 +1,35 @@
+package member
+
+import (
+	"net/http"
+
+	"github.com/eugenetolok/evento/internal/evento/credential"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// getMemberCredential issues a signed credential for the badge qr code
+func getMemberCredential(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var member model.Member
+	if err := db.Select("id", "company_id", "blocked").First(&member, id).Error; err != nil {
+		return c.String(http.StatusNotFound, `Участник не найден`)
+	}
+	var company model.Company
+	if err := db.Preload("User").First(&company, member.CompanyID).Error; err != nil {
+		return c.String(http.StatusNotFound, `ID компании неверный`)
+	}
+	if !utils.CheckCompanyGetPermission(c, company) {
+		return c.String(http.StatusUnauthorized, `У вас недостаточно прав, чтобы сделать данный запрос`)
+	}
+	if member.Blocked {
+		return c.String(http.StatusBadRequest, `Участник заблокирован`)
+	}
+	code, claims, err := credential.Issue(member.ID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"credential": code, "claims": claims})
+}
@@ -109,6 +109,8 @@ func createMember(c echo.Context) error {
 	member.CompanyID = company.ID // Set company ID *before* potentially needing it in validation
 
 	// Use transaction for create + limit check
+	unlock := lockMemberLimits(db, company.ID)
+	defer unlock()
 	err = db.Transaction(func(tx *gorm.DB) error {
 		// Call memberWriteLogic (includes binding AND limit checks)
 		// Pass nil for memberID because we are creating
@@ -137,6 +139,9 @@ func createMember(c echo.Context) error {
 				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Ошибка генерации штрихкода: %v", err))
 			}
 		}
+		if err := recordIssuedBarcode(tx, member.ID, member.Barcode); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Ошибка генерации штрихкода: %v", err))
+		}
 		return nil // Commit transaction
 	})
 
@@ -179,6 +184,8 @@ func updateMember(c echo.Context) error {
 	}
 
 	// Use transaction for update + limit check
+	unlock := lockMemberLimits(db, member.CompanyID)
+	defer unlock()
 	err = db.Transaction(func(tx *gorm.DB) error {
 		// Call memberWriteLogic (includes binding AND limit checks)
 		// Pass the existing member's ID
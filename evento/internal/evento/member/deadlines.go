@@ -0,0 +1,144 @@
+package member
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// registrationWindow is an accreditation or event with the deadline effective for a company
+type registrationWindow struct {
+	ID         uuid.UUID  `json:"id"`
+	Kind       string     `json:"kind"`
+	Name       string     `json:"name"`
+	Deadline   *time.Time `json:"deadline,omitempty"`
+	Overridden bool       `json:"overridden"`
+	Open       bool       `json:"open"`
+}
+
+// registrationWindows returns effective deadlines of all accreditations and events for the company
+func registrationWindows(tx *gorm.DB, companyID uuid.UUID, now time.Time) (map[uuid.UUID]registrationWindow, error) {
+	var overrides []model.CompanyDeadlineOverride
+	if err := tx.Where("company_id = ?", companyID).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	overridden := map[uuid.UUID]*time.Time{}
+	for _, override := range overrides {
+		if override.AccreditationID != uuid.Nil {
+			overridden[override.AccreditationID] = override.Deadline
+		}
+		if override.EventID != uuid.Nil {
+			overridden[override.EventID] = override.Deadline
+		}
+	}
+
+	windows := map[uuid.UUID]registrationWindow{}
+	add := func(id uuid.UUID, kind, name string, deadline *time.Time) {
+		window := registrationWindow{ID: id, Kind: kind, Name: name, Deadline: deadline}
+		if companyDeadline, ok := overridden[id]; ok {
+			window.Deadline = companyDeadline
+			window.Overridden = true
+		}
+		window.Open = window.Deadline == nil || now.Before(*window.Deadline)
+		windows[id] = window
+	}
+
+	var accreditations []model.Accreditation
+	if err := tx.Select("id", "name", "registration_deadline").Find(&accreditations).Error; err != nil {
+		return nil, err
+	}
+	for _, accreditation := range accreditations {
+		add(accreditation.ID, "accreditation", accreditation.Name, accreditation.RegistrationDeadline)
+	}
+	var events []model.Event
+	if err := tx.Select("id", "name", "registration_deadline").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		add(event.ID, "event", event.Name, event.RegistrationDeadline)
+	}
+	return windows, nil
+}
+
+// checkRegistrationDeadlines fails with the list of closed accreditations and events among ids
+func checkRegistrationDeadlines(tx *gorm.DB, companyID uuid.UUID, ids []uuid.UUID) error {
+	windows, err := registrationWindows(tx, companyID, time.Now())
+	if err != nil {
+		return err
+	}
+	return closedRegistrationError(windows, ids)
+}
+
+// closedRegistrationError describes which of ids are closed for registration, nil when all are open
+func closedRegistrationError(windows map[uuid.UUID]registrationWindow, ids []uuid.UUID) error {
+	var closed []string
+	for _, id := range ids {
+		window, ok := windows[id]
+		if !ok || window.Open {
+			continue
+		}
+		kind := "аккредитация"
+		if window.Kind == "event" {
+			kind = "мероприятие"
+		}
+		closed = append(closed, fmt.Sprintf("%s «%s» (до %s)", kind, window.Name, window.Deadline.Local().Format("02.01.2006 15:04")))
+	}
+	if len(closed) > 0 {
+		return fmt.Errorf("Регистрация закрыта: %s", strings.Join(closed, ", "))
+	}
+	return nil
+}
+
+// deadlinesApply reports whether registration deadlines restrict the role
+func deadlinesApply(role string) bool {
+	return role == "company"
+}
+
+// addedEventIDs returns ids from requested that the member does not have yet
+func addedEventIDs(current []model.Event, requested []uuid.UUID) []uuid.UUID {
+	existing := map[uuid.UUID]bool{}
+	for _, event := range current {
+		existing[event.ID] = true
+	}
+	var added []uuid.UUID
+	for _, id := range requested {
+		if !existing[id] {
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+// getRegistrationWindows lists accreditations and events with deadlines effective for the company
+func getRegistrationWindows(c echo.Context) error {
+	companyID, err := utils.ResolveCompanyIDForManage(c, db, c.QueryParam("company_id"))
+	if err != nil {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	windows, err := registrationWindows(db, companyID, time.Now())
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	response := make([]registrationWindow, 0, len(windows))
+	for _, window := range windows {
+		response = append(response, window)
+	}
+	sort.Slice(response, func(i, j int) bool {
+		if response[i].Kind != response[j].Kind {
+			return response[i].Kind < response[j].Kind
+		}
+		return response[i].Name < response[j].Name
+	})
+	return c.JSON(http.StatusOK, response)
+}
@@ -1,6 +1,7 @@
 package member
 
 import (
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
@@ -11,11 +12,17 @@ import (
 // logMemberHistory creates and saves an MemberHistory record within a transaction.
 func logMemberHistory(tx *gorm.DB, c echo.Context, memberID uuid.UUID, changeType string, details string) error {
 	userID, _ := utils.GetUser(c)
+	return logMemberHistoryAs(tx, userID, logger.EchoRequestID(c), memberID, changeType, details)
+}
+
+// logMemberHistoryAs records a history entry for the given user outside of a request
+func logMemberHistoryAs(tx *gorm.DB, userID uuid.UUID, requestID string, memberID uuid.UUID, changeType string, details string) error {
 	history := model.MemberHistory{
 		MemberID:   memberID,
 		UserID:     userID,
 		ChangeType: changeType,
 		Details:    details,
+		RequestID:  requestID,
 	}
 	return tx.Create(&history).Error
 }
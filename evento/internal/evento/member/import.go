@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
@@ -191,7 +192,7 @@ func importMembers(c echo.Context) error {
 	if !utils.CheckUserWritePermission(c, db) {
 		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
 	}
-	_, userRole := utils.GetUser(c)
+	userID, userRole := utils.GetUser(c)
 	companyID, err := utils.ResolveCompanyIDForManage(c, db, c.QueryParam("company_id"))
 	if err != nil {
 		if httpErr, ok := err.(*echo.HTTPError); ok {
@@ -219,15 +220,55 @@ func importMembers(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
+	imported, err := ImportMembersXLSX(db, xlsxFile, MemberImportOptions{
+		CompanyID:        companyID,
+		UserID:           userID,
+		IncludeHidden:    userRole == "admin",
+		RequestID:        logger.EchoRequestID(c),
+		EnforceDeadlines: deadlinesApply(userRole),
+	})
+	if err != nil {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.String(http.StatusOK, fmt.Sprintf("Успешно импортировано участников: %d", imported))
+}
+
+// MemberImportOptions describes on whose behalf members are imported
+type MemberImportOptions struct {
+	CompanyID uuid.UUID
+	UserID    uuid.UUID
+	// IncludeHidden allows hidden accreditations, admins only
+	IncludeHidden bool
+	// RequestID is stored in member history for audit correlation
+	RequestID string
+	// EnforceDeadlines rejects rows for accreditations and events closed for registration
+	EnforceDeadlines bool
+}
+
+// ImportMembersXLSX validates the members template and creates all members in one transaction.
+// Validation errors are returned as *echo.HTTPError with a user-facing message.
+func ImportMembersXLSX(database *gorm.DB, xlsxFile *xlsx.File, opts MemberImportOptions) (int, error) {
+	if len(xlsxFile.Sheets) == 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Файл не содержит листов")
+	}
+	companyID := opts.CompanyID
 	sheet := xlsxFile.Sheets[0]
 
+	// limits are checked against the members below, keep other writes of the company out until the import commits
+	unlock := lockMemberLimits(database, companyID)
+	defer unlock()
+
 	var company model.Company
 	// Загружаем компанию вместе со ВСЕМИ ее лимитами один раз для эффективности
-	if err := db.Preload("Members").Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").First(&company, companyID).Error; err != nil {
+	if err := database.Preload("Members").Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").First(&company, companyID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.String(http.StatusNotFound, `{"error":"company is not found"}`)
+			return 0, echo.NewHTTPError(http.StatusNotFound, `{"error":"company is not found"}`)
 		}
-		return c.String(http.StatusInternalServerError, err.Error())
+		return 0, err
 	}
 
 	// Создаем карты разрешенных для компании сущностей для быстрой проверки прав.
@@ -263,18 +304,19 @@ func importMembers(c echo.Context) error {
 	maxReadRow := realMaxRow - 2 // Количество строк с данными (не включая заголовки)
 	currentLimit := int(company.MembersLimit) - len(company.Members)
 	if maxReadRow > currentLimit {
-		return c.String(http.StatusBadRequest, fmt.Sprintf("Превышено количество загружаемых участников.\nТекущий лимит: %d, в файле: %d", currentLimit, maxReadRow))
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Превышено количество загружаемых участников.\nТекущий лимит: %d, в файле: %d", currentLimit, maxReadRow))
 	}
 
 	// Загружаем все возможные аккредитации, мероприятия и зоны для сопоставления по имени
 	var allAccreditations []model.Accreditation
-	if userRole == "admin" {
-		err = db.Order("position desc").Find(&allAccreditations).Error
+	var err error
+	if opts.IncludeHidden {
+		err = database.Order("position desc").Find(&allAccreditations).Error
 	} else {
-		err = db.Order("position desc").Where("hidden = ?", false).Find(&allAccreditations).Error
+		err = database.Order("position desc").Where("hidden = ?", false).Find(&allAccreditations).Error
 	}
 	if err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+		return 0, err
 	}
 	accreditationMap := make(map[string]uuid.UUID)
 	for _, accreditation := range allAccreditations {
@@ -282,13 +324,20 @@ func importMembers(c echo.Context) error {
 	}
 
 	var allEvents []model.Event
-	if err := db.Find(&allEvents).Error; err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Find(&allEvents).Error; err != nil {
+		return 0, err
 	}
 
 	var allGates []model.Gate
-	if err := db.Find(&allGates).Error; err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Find(&allGates).Error; err != nil {
+		return 0, err
+	}
+
+	var windows map[uuid.UUID]registrationWindow
+	if opts.EnforceDeadlines {
+		if windows, err = registrationWindows(database, companyID, time.Now()); err != nil {
+			return 0, err
+		}
 	}
 
 	var membersToCreate []model.Member
@@ -296,7 +345,7 @@ func importMembers(c echo.Context) error {
 
 	headerRow, err := sheet.Row(0)
 	if err != nil {
-		return c.String(http.StatusBadRequest, "Не удалось прочитать строку с заголовками в файле.")
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Не удалось прочитать строку с заголовками в файле.")
 	}
 
 	// Итерируемся по строкам с данными
@@ -356,6 +405,12 @@ func importMembers(c echo.Context) error {
 		if hasPermissionError {
 			continue // Если были ошибки прав, переходим к следующей строке
 		}
+		if opts.EnforceDeadlines {
+			if err := closedRegistrationError(windows, append([]uuid.UUID{accreditationID}, eventIDs...)); err != nil {
+				errorsList = append(errorsList, fmt.Sprintf("Строка %d: %s", i+1, err.Error()))
+				continue
+			}
+		}
 
 		// 4. Формирование объекта участника
 		member := model.Member{
@@ -371,7 +426,7 @@ func importMembers(c echo.Context) error {
 		}
 
 		// Предполагается, что эта функция корректно заполняет member.Events и member.Gates
-		err = membersFillLite(c, &member, eventIDs, gateIDs)
+		err = membersFillLite(database, &member, eventIDs, gateIDs)
 		if err != nil {
 			errorsList = append(errorsList, fmt.Sprintf("Строка %d: %s", i+1, err.Error()))
 			continue
@@ -380,7 +435,7 @@ func importMembers(c echo.Context) error {
 		// 5. Проверка количественных ЛИМИТОВ
 		// Эта проверка теперь вызывается только после того, как мы убедились, что права на сущности есть.
 		// Мы передаем сюда уже накопленный список участников для создания + текущего.
-		err = checkCompanyLimits(companyID, accreditationID, eventIDs, gateIDs, append(membersToCreate, member))
+		err = checkCompanyLimits(database, companyID, accreditationID, eventIDs, gateIDs, append(membersToCreate, member))
 		if err != nil {
 			errorsList = append(errorsList, fmt.Sprintf("Строка %d: %s", i+1, err.Error()))
 			continue
@@ -400,11 +455,11 @@ func importMembers(c echo.Context) error {
 			finalErrors = append(finalErrors, e)
 		}
 		sort.Strings(finalErrors)
-		return c.String(http.StatusBadRequest, fmt.Sprintf("Некоторые участники не могут быть импортированы:\n\n%s", strings.Join(finalErrors, "\n")))
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Некоторые участники не могут быть импортированы:\n\n%s", strings.Join(finalErrors, "\n")))
 	}
 
 	// Если ошибок нет, создаем всех участников в одной транзакции
-	err = db.Transaction(func(tx *gorm.DB) error {
+	err = database.Transaction(func(tx *gorm.DB) error {
 		for _, member := range membersToCreate {
 			if err := tx.Create(&member).Error; err != nil {
 				if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "duplicate key") {
@@ -422,18 +477,21 @@ func importMembers(c echo.Context) error {
 					return fmt.Errorf("Не удалось сгенерировать штрихкод для участника %s %s %s: %w", member.Surname, member.Name, member.Middlename, err)
 				}
 			}
+			if err := recordIssuedBarcode(tx, member.ID, member.Barcode); err != nil {
+				return fmt.Errorf("Не удалось сгенерировать штрихкод для участника %s %s %s: %w", member.Surname, member.Name, member.Middlename, err)
+			}
 			tx.Preload("Accreditation.Gates").Preload("Events").Preload("Gates").First(&member, member.ID)
 			memberDetails, _ := json.Marshal(member)
-			logMemberHistory(tx, c, member.ID, "create", string(memberDetails))
+			logMemberHistoryAs(tx, opts.UserID, opts.RequestID, member.ID, "create", string(memberDetails))
 		}
 		return nil
 	})
 
 	if err != nil {
-		return c.String(http.StatusInternalServerError, "Не удалось импортировать участников: \n\n"+err.Error())
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "Не удалось импортировать участников: \n\n"+err.Error())
 	}
 
-	return c.String(http.StatusOK, fmt.Sprintf("Успешно импортировано участников: %d", len(membersToCreate)))
+	return len(membersToCreate), nil
 }
 
 func parseBool(value string) bool {
@@ -473,9 +531,9 @@ func parseDate(cell *xlsx.Cell) (time.Time, error) {
 	return time.Parse("02.01.2006", cell.String())
 }
 
-func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs []uuid.UUID, gateIDs []uuid.UUID, newMembers []model.Member) error {
+func checkCompanyLimits(tx *gorm.DB, companyID uuid.UUID, accreditationID uuid.UUID, eventIDs []uuid.UUID, gateIDs []uuid.UUID, newMembers []model.Member) error {
 	var company model.Company
-	if err := db.Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").First(&company, companyID).Error; err != nil {
+	if err := tx.Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").First(&company, companyID).Error; err != nil {
 		return err
 	}
 
@@ -483,7 +541,7 @@ func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs
 	for _, limit := range company.AccreditationLimits {
 		if limit.AccreditationID == accreditationID {
 			var count int64
-			if err := db.Model(&model.Member{}).Where("accreditation_id = ? AND company_id = ?", accreditationID, companyID).Count(&count).Error; err != nil {
+			if err := tx.Model(&model.Member{}).Where("accreditation_id = ? AND company_id = ?", accreditationID, companyID).Count(&count).Error; err != nil {
 				return err
 			}
 			// Add the number of new members with the same accreditation
@@ -494,7 +552,7 @@ func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs
 			}
 			if count >= int64(limit.Limit+1) {
 				var accreditation model.Accreditation
-				db.First(&accreditation, accreditationID)
+				tx.First(&accreditation, accreditationID)
 				return fmt.Errorf("Достигнут лимит аккредитации '%s'. Лимит: %d", accreditation.Name, limit.Limit)
 			}
 		}
@@ -505,7 +563,7 @@ func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs
 		for _, limit := range company.EventLimits {
 			if limit.EventID == eventID {
 				var count int64
-				if err := db.Model(&model.Member{}).
+				if err := tx.Model(&model.Member{}).
 					Joins("JOIN member_events ON member_events.member_id = members.id").
 					Where("member_events.event_id = ? AND members.company_id = ?", eventID, companyID).
 					Count(&count).Error; err != nil {
@@ -521,7 +579,7 @@ func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs
 				}
 				if count >= int64(limit.Limit+1) {
 					var event model.Event
-					db.First(&event, eventID)
+					tx.First(&event, eventID)
 					return fmt.Errorf("Достигнут лимит мероприятия '%s'. Лимит: %d", event.Name, limit.Limit)
 				}
 			}
@@ -533,7 +591,7 @@ func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs
 		for _, limit := range company.GateLimits {
 			if limit.GateID == gateID {
 				var count int64
-				if err := db.Model(&model.Member{}).
+				if err := tx.Model(&model.Member{}).
 					Joins("JOIN member_gates ON member_gates.member_id = members.id").
 					Where("member_gates.gate_id = ? AND members.company_id = ?", gateID, companyID).
 					Count(&count).Error; err != nil {
@@ -549,7 +607,7 @@ func checkCompanyLimits(companyID uuid.UUID, accreditationID uuid.UUID, eventIDs
 				}
 				if count >= int64(limit.Limit+1) {
 					var gate model.Gate
-					db.First(&gate, gateID)
+					tx.First(&gate, gateID)
 					return fmt.Errorf("Достигнут лимит зоны '%s'. Лимит: %d", gate.Name, limit.Limit)
 				}
 			}
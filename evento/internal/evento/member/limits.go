@@ -8,13 +8,31 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/eugenetolok/evento/pkg/keylock"
 	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // ... (keep existing functions like getCompanyMembers, getEditorMembers, setState etc.)
 
+// limitLocks serializes limit checks and member writes per company: the check and the write
+// must happen under one lock, otherwise two parallel requests can both pass the check
+var limitLocks = keylock.New()
+
+// capacityLockKey is taken by every company while global capacity is configured, since capacity spans companies
+const capacityLockKey = "capacity"
+
+// lockMemberLimits must be taken before the transaction that validates limits and writes members of the company
+func lockMemberLimits(tx *gorm.DB, companyID uuid.UUID) (unlock func()) {
+	keys := []string{companyID.String()}
+	if utils.CapacityConfigured(tx) {
+		keys = append(keys, capacityLockKey)
+	}
+	return limitLocks.Lock(keys...)
+}
+
 // validateMemberLimits checks if adding/updating a member violates company limits.
 // memberID is nil for creation, or the existing member's ID for update.
 func validateMemberLimits(tx *gorm.DB, companyID, accreditationID uuid.UUID, eventIDs, gateIDs []uuid.UUID, memberID *uuid.UUID) error {
@@ -170,5 +188,10 @@ func validateMemberLimits(tx *gorm.DB, companyID, accreditationID uuid.UUID, eve
 		}
 	}
 
+	// --- Global Capacity Check ---
+	if err := utils.CheckRegisteredCapacity(tx, accreditationID, eventIDs, gateIDs, memberID); err != nil {
+		return err
+	}
+
 	return nil // All limits are respected
 }
@@ -0,0 +1,92 @@
+package member
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openLimitsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "limits.db") + "?_busy_timeout=10000"
+	database, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&model.User{}, &model.Company{}, &model.Accreditation{}, &model.Event{}, &model.Gate{},
+		&model.CompanyAccreditationLimit{}, &model.CompanyEventLimit{}, &model.CompanyGateLimit{},
+		&model.Member{}, &model.MemberHistory{}, &model.MemberBarcode{}); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// TestCreateMemberLimitsUnderConcurrency fires parallel creates at one company and expects
+// exactly the accreditation limit to be allocated
+func TestCreateMemberLimitsUnderConcurrency(t *testing.T) {
+	const limit = 5
+	const requests = 25
+
+	db = openLimitsTestDB(t)
+	admin := model.User{Username: "admin", Role: "admin"}
+	company := model.Company{Name: "Stress", MembersLimit: 100}
+	accreditation := model.Accreditation{Name: "Stress"}
+	for _, value := range []interface{}{&admin, &company, &accreditation} {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&model.CompanyAccreditationLimit{CompanyID: company.ID, AccreditationID: accreditation.ID, Limit: limit}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	var wg sync.WaitGroup
+	codes := make([]int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"surname":"Stress","name":"Member","middlename":"%d","document":"DOC%d","birth":"%s","accreditation_id":"%s"}`,
+				i, i, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339), accreditation.ID)
+			req := httptest.NewRequest(http.MethodPost, "/?company_id="+company.ID.String(), strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set("user", &jwt.Token{Claims: &model.JwtCustomClaims{ID: admin.ID, Role: "admin"}})
+			if err := createMember(c); err != nil {
+				t.Error(err)
+			}
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusBadRequest:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	var count int64
+	db.Model(&model.Member{}).Where("company_id = ? AND accreditation_id = ?", company.ID, accreditation.ID).Count(&count)
+	if created != limit || count != limit {
+		t.Fatalf("expected %d members, got %d created responses and %d rows", limit, created, count)
+	}
+}
@@ -1,12 +1,14 @@
 package member
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/eugenetolok/evento/internal/evento/bangle"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/google/uuid"
@@ -120,6 +122,17 @@ func memberWriteLogic(c echo.Context, member *model.Member, tx *gorm.DB, memberI
 	}
 	// --- END LIMIT CHECK ---
 
+	if _, role := utils.GetUser(c); deadlinesApply(role) {
+		deadlineIDs := append([]uuid.UUID{input.AccreditationID}, input.EventIDs...)
+		if memberID != nil && *memberID != uuid.Nil {
+			// on update the current accreditation must be open too, events only when newly added
+			deadlineIDs = append([]uuid.UUID{input.AccreditationID, member.AccreditationID}, addedEventIDs(member.Events, input.EventIDs)...)
+		}
+		if err := checkRegistrationDeadlines(tx, companyIDToCheck, deadlineIDs); err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+	}
+
 	// Update the member fields (keep existing logic)
 	if input.Surname != "" {
 		member.Surname = input.Surname
@@ -173,11 +186,11 @@ func memberWriteLogic(c echo.Context, member *model.Member, tx *gorm.DB, memberI
 	return nil // Return nil if binding and limit checks are successful
 }
 
-func membersFillLite(c echo.Context, member *model.Member, eventIDs, gateIDs []uuid.UUID) error {
+func membersFillLite(tx *gorm.DB, member *model.Member, eventIDs, gateIDs []uuid.UUID) error {
 	// Update or create accreditation limits
 	var events []model.Event
-	if err := db.Where("id in (?)", eventIDs).Find(&events).Error; err != nil {
-		return c.String(http.StatusNotFound, "Мероприятие не найдено")
+	if err := tx.Where("id in (?)", eventIDs).Find(&events).Error; err != nil {
+		return errors.New("Мероприятие не найдено")
 	}
 	// Remove gates that are not included in the GateIDs field
 	var eventIDsMap = make(map[uuid.UUID]bool)
@@ -186,14 +199,14 @@ func membersFillLite(c echo.Context, member *model.Member, eventIDs, gateIDs []u
 	}
 	for _, event := range member.Events {
 		if !eventIDsMap[event.ID] {
-			db.Model(&member).Association("Events").Delete(&event)
+			tx.Model(&member).Association("Events").Delete(&event)
 		}
 	}
 	member.Events = events
 
 	var gates []model.Gate
-	if err := db.Where("id in (?)", gateIDs).Find(&gates).Error; err != nil {
-		return c.String(http.StatusNotFound, "Зона не найдена")
+	if err := tx.Where("id in (?)", gateIDs).Find(&gates).Error; err != nil {
+		return errors.New("Зона не найдена")
 	}
 	// Remove gates that are not included in the GateIDs field
 	var gateIDsMap = make(map[uuid.UUID]bool)
@@ -202,19 +215,23 @@ func membersFillLite(c echo.Context, member *model.Member, eventIDs, gateIDs []u
 	}
 	for _, gate := range member.Gates {
 		if !gateIDsMap[gate.ID] {
-			db.Model(&member).Association("Gates").Delete(&gate)
+			tx.Model(&member).Association("Gates").Delete(&gate)
 		}
 	}
 	member.Gates = gates
 	return nil
 }
 
-// print member
-func print(c echo.Context) error {
+// member give bangle, the optional body {"type_id", "point_id", "uid"} records the bangle for stock and gates
+func giveBangle(c echo.Context) error {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		return c.String(http.StatusBadRequest, `{"error":"invalid id"}`)
 	}
+	var input bangle.IssueInput
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 	var member model.Member
 	if err := db.First(&member, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -222,63 +239,37 @@ func print(c echo.Context) error {
 		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
-	member.PrintCount = member.PrintCount + 1
-	db.Save(&member)
-
-	var memberPrint model.MemberPrint
-	memberPrint.MemberID = id
-	if errCreatePrint := db.Create(&memberPrint).Error; errCreatePrint != nil {
-		fmt.Println("unable to create memberPrint", errCreatePrint)
-	}
 
-	return c.JSON(http.StatusOK, member)
-}
-
-// MemberIDs ...
-type MemberIDs struct {
-	MemberIDs []uuid.UUID `json:"memberIds"`
-}
-
-// massPrint members
-func massPrint(c echo.Context) error {
-	var ids MemberIDs
-	if err := c.Bind(&ids); err != nil {
-		return c.String(http.StatusBadRequest, `{"error":"invalid request body"}`)
-	}
-	var membersToSave []model.Member
-	for _, id := range ids.MemberIDs {
-		var member model.Member
-		if err := db.First(&member, id).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return c.String(http.StatusInternalServerError, err.Error())
+	userID, _ := utils.GetUser(c)
+	unlock := bangle.LockIssue(input)
+	defer unlock()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		issue, revoked, err := bangle.Issue(tx, member, input, userID)
+		if err != nil {
+			return err
+		}
+		for _, credential := range revoked {
+			details, _ := json.Marshal(credential)
+			if err := logMemberHistory(tx, c, member.ID, "credential_unbound", string(details)); err != nil {
+				return err
 			}
 		}
-
-		member.PrintCount = member.PrintCount + 1
-		membersToSave = append(membersToSave, member)
-	}
-	db.Save(&membersToSave)
-
-	return c.String(http.StatusOK, `{"message":"print count updated for specified members"}`)
-}
-
-// member give bangle
-func giveBangle(c echo.Context) error {
-	id, err := uuid.Parse(c.Param("id"))
+		if issue.UID != "" {
+			details, _ := json.Marshal(issue)
+			if err := logMemberHistory(tx, c, member.ID, "credential_bound", string(details)); err != nil {
+				return err
+			}
+		}
+		member.GivenBangle = true
+		member.GivenBangleCount = member.GivenBangleCount + 1
+		return tx.Save(&member).Error
+	})
 	if err != nil {
-		return c.String(http.StatusBadRequest, `{"error":"invalid id"}`)
-	}
-	var member model.Member
-	if err := db.First(&member, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.String(http.StatusNotFound, `{"error":"member is not found"}`)
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, httpErr.Message.(string))
 		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	member.GivenBangle = true
-	member.GivenBangleCount = member.GivenBangleCount + 1
-	db.Save(&member)
-
 	return c.JSON(http.StatusOK, member)
 }
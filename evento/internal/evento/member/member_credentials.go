@@ -0,0 +1,213 @@
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var memberCredentialTypes = map[string]bool{model.CredentialBarcode: true, model.CredentialNFC: true, model.CredentialBangle: true}
+
+// OfflineCredential is a credential of the member in the offline feed
+type OfflineCredential struct {
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+type memberCredentialInput struct {
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	Status     string     `json:"status"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+}
+
+func getMemberCredentials(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var credentials []model.MemberCredential
+	if err := db.Where("member_id = ?", id).Order("bound_at DESC").Find(&credentials).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// bindMemberCredential binds an nfc card, a bangle or an extra barcode to the member
+func bindMemberCredential(c echo.Context) error {
+	id, _ := uuid.Parse(c.Param("id"))
+	var input memberCredentialInput
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	input.Value = strings.TrimSpace(input.Value)
+	if !memberCredentialTypes[input.Type] {
+		return c.String(http.StatusBadRequest, "Тип идентификатора: barcode, nfc или bangle")
+	}
+	if input.Value == "" {
+		return c.String(http.StatusBadRequest, "Укажите значение идентификатора")
+	}
+	if input.ValidFrom != nil && input.ValidUntil != nil && !input.ValidUntil.After(*input.ValidFrom) {
+		return c.String(http.StatusBadRequest, "Срок действия заканчивается раньше, чем начинается")
+	}
+	var member model.Member
+	if err := db.Select("id").First(&member, id).Error; err != nil {
+		return c.String(http.StatusNotFound, `Участник не найден`)
+	}
+	taken, err := utils.CredentialTaken(db, input.Value)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if taken {
+		return c.String(http.StatusBadRequest, "Идентификатор уже привязан")
+	}
+	credential := model.MemberCredential{
+		MemberID:   member.ID,
+		Type:       input.Type,
+		Value:      input.Value,
+		Status:     model.CredentialActive,
+		ValidFrom:  input.ValidFrom,
+		ValidUntil: input.ValidUntil,
+		BoundAt:    time.Now(),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&credential).Error; err != nil {
+			return err
+		}
+		details, _ := json.Marshal(credential)
+		return logMemberHistory(tx, c, member.ID, "credential_bound", string(details))
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, credential)
+}
+
+// updateMemberCredential suspends or resumes a credential and changes its validity
+func updateMemberCredential(c echo.Context) error {
+	credential, err := findBoundCredential(c)
+	if err != nil {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	var input memberCredentialInput
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if input.Status != model.CredentialActive && input.Status != model.CredentialSuspended {
+		return c.String(http.StatusBadRequest, "Статус: active или suspended")
+	}
+	if input.ValidFrom != nil && input.ValidUntil != nil && !input.ValidUntil.After(*input.ValidFrom) {
+		return c.String(http.StatusBadRequest, "Срок действия заканчивается раньше, чем начинается")
+	}
+	credential.Status = input.Status
+	credential.ValidFrom = input.ValidFrom
+	credential.ValidUntil = input.ValidUntil
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&credential).Error; err != nil {
+			return err
+		}
+		details, _ := json.Marshal(credential)
+		return logMemberHistory(tx, c, credential.MemberID, "credential_updated", string(details))
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, credential)
+}
+
+// unbindMemberCredential revokes a credential, it stays in the list of the member
+func unbindMemberCredential(c echo.Context) error {
+	credential, err := findBoundCredential(c)
+	if err != nil {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	now := time.Now()
+	credential.Status = model.CredentialRevoked
+	credential.UnboundAt = &now
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&credential).Error; err != nil {
+			return err
+		}
+		details, _ := json.Marshal(credential)
+		return logMemberHistory(tx, c, credential.MemberID, "credential_unbound", string(details))
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, credential)
+}
+
+// findBoundCredential loads the credential of the member which is not unbound, errors are *echo.HTTPError
+func findBoundCredential(c echo.Context) (model.MemberCredential, error) {
+	var credential model.MemberCredential
+	memberID, _ := uuid.Parse(c.Param("id"))
+	credentialID, err := uuid.Parse(c.Param("credentialId"))
+	if err != nil {
+		return credential, echo.NewHTTPError(http.StatusBadRequest, "Неверный идентификатор")
+	}
+	if err := db.Where("id = ? AND member_id = ?", credentialID, memberID).First(&credential).Error; err != nil {
+		return credential, echo.NewHTTPError(http.StatusNotFound, "Идентификатор не найден")
+	}
+	if credential.Status == model.CredentialRevoked {
+		return credential, echo.NewHTTPError(http.StatusBadRequest, "Идентификатор уже отвязан")
+	}
+	return credential, nil
+}
+
+// findMemberCredential looks up a scanned code among credentials, the latest binding wins
+func findMemberCredential(value string) (model.MemberCredential, bool) {
+	var credential model.MemberCredential
+	if value == "" || db.Where("value = ?", value).Order("bound_at DESC").First(&credential).Error != nil {
+		return credential, false
+	}
+	return credential, true
+}
+
+// memberCredentialDenial explains why a credential does not pass at now, empty when it passes
+func memberCredentialDenial(credential model.MemberCredential, now time.Time) string {
+	switch {
+	case credential.Status == model.CredentialRevoked && credential.UnboundAt != nil:
+		return "Идентификатор отвязан " + credential.UnboundAt.Format("02.01.2006 15:04")
+	case credential.Status == model.CredentialRevoked:
+		return "Идентификатор отвязан"
+	case credential.Status == model.CredentialSuspended:
+		return "Идентификатор приостановлен"
+	case credential.ValidFrom != nil && now.Before(*credential.ValidFrom):
+		return "Идентификатор действует с " + credential.ValidFrom.Format("02.01.2006 15:04")
+	case credential.ValidUntil != nil && !now.Before(*credential.ValidUntil):
+		return "Срок действия идентификатора истёк " + credential.ValidUntil.Format("02.01.2006 15:04")
+	}
+	return ""
+}
+
+// offlineMemberCredentials returns credentials which are not revoked or suspended by member
+func offlineMemberCredentials() (map[uuid.UUID][]OfflineCredential, error) {
+	var credentials []model.MemberCredential
+	if err := db.Where("status = ?", model.CredentialActive).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	result := map[uuid.UUID][]OfflineCredential{}
+	for _, credential := range credentials {
+		result[credential.MemberID] = append(result[credential.MemberID], OfflineCredential{
+			Type:       credential.Type,
+			Value:      credential.Value,
+			ValidFrom:  credential.ValidFrom,
+			ValidUntil: credential.ValidUntil,
+		})
+	}
+	return result, nil
+}
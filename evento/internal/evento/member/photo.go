@@ -4,12 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/eugenetolok/evento/pkg/imaging"
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils" // For permission checks
 	"github.com/google/uuid"
@@ -18,6 +20,10 @@ import (
 )
 
 const maxUploadSize = 5 * 1024 * 1024 // 5 MB
+
+// thumbnailDir keeps photo thumbnails inside the photo directory
+const thumbnailDir = "thumbnails"
+
 var allowedMimeTypes = map[string]bool{
 	"image/jpeg": true,
 	"image/png":  true,
@@ -45,7 +51,7 @@ func uploadMemberPhoto(c echo.Context) error {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return echo.NewHTTPError(http.StatusNotFound, "Member not found")
 		}
-		log.Printf("Error fetching member %s: %v", memberID, err)
+		logger.FromEcho(c).Error("failed to fetch member", "member_id", memberID, "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error fetching member")
 	}
 
@@ -53,7 +59,7 @@ func uploadMemberPhoto(c echo.Context) error {
 	// Adapt this check based on your exact requirements (who can upload?)
 	var companyForCheck model.Company // Need company data for the check
 	if err := db.Preload("User").First(&companyForCheck, member.CompanyID).Error; err != nil {
-		log.Printf("Error fetching company %s for permission check: %v", member.CompanyID, err)
+		logger.FromEcho(c).Error("failed to fetch company for permission check", "company_id", member.CompanyID, "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error checking permissions")
 	}
 	if !utils.CheckCompanyManagePermission(c, companyForCheck) {
@@ -63,89 +69,105 @@ func uploadMemberPhoto(c echo.Context) error {
 	// 4. Get File from Request
 	file, err := c.FormFile("photo") // "photo" is the expected form field name
 	if err != nil {
-		log.Printf("Error getting form file: %v", err)
+		logger.FromEcho(c).Debug("missing photo form file", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Missing or invalid 'photo' form field")
 	}
 
-	// 5. Validate File Size
-	if file.Size > maxUploadSize {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("File size exceeds limit of %d MB", maxUploadSize/1024/1024))
-	}
-
-	// 6. Validate File Type
 	src, err := file.Open()
 	if err != nil {
-		log.Printf("Error opening uploaded file: %v", err)
+		logger.FromEcho(c).Error("failed to open uploaded file", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open uploaded file")
 	}
 	defer src.Close()
 
+	// 5. Validate, normalize and save the photo with its thumbnail
+	if err := saveMemberPhoto(c, &member, src, file.Size); err != nil {
+		return err
+	}
+
+	// 6. Return Success
+	logger.FromEcho(c).Info("member photo uploaded", "member_id", memberID, "filename", member.PhotoFilename)
+	// Return the updated member object or just a success message
+	return c.JSON(http.StatusOK, member) // Return updated member
+}
+
+// saveMemberPhoto validates size and type of the upload, normalizes it and replaces the member photo,
+// errors are *echo.HTTPError
+func saveMemberPhoto(c echo.Context, member *model.Member, src io.ReadSeeker, size int64) error {
+	if size > maxUploadSize {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Размер файла больше %d МБ", maxUploadSize/1024/1024))
+	}
+
 	// Read first 512 bytes to determine MIME type
 	buffer := make([]byte, 512)
-	_, err = src.Read(buffer)
+	_, err := src.Read(buffer)
 	if err != nil && err != io.EOF {
-		log.Printf("Error reading file header: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file type")
+		logger.FromEcho(c).Error("failed to read file header", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Не удалось прочитать файл")
 	}
-	// Reset reader to the beginning
-	_, err = src.Seek(0, io.SeekStart)
-	if err != nil {
-		log.Printf("Error seeking file: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed processing file")
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		logger.FromEcho(c).Error("failed to seek uploaded file", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Не удалось прочитать файл")
 	}
-
 	mimeType := http.DetectContentType(buffer)
 	if !allowedMimeTypes[mimeType] {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid file type: %s. Allowed types: %v", mimeType, getAllowedTypesString()))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Недопустимый тип файла: %s, допустимы %s", mimeType, getAllowedTypesString()))
 	}
 
-	// 7. Generate Unique Filename (member_uuid.original_extension)
-	originalExt := filepath.Ext(file.Filename)
-	if originalExt == "" { // Basic check for extension
-		originalExt = getExtensionFromMime(mimeType) // Fallback based on detected MIME
-		if originalExt == "" {
-			return echo.NewHTTPError(http.StatusBadRequest, "Cannot determine file extension")
-		}
+	// Auto-orient, crop to the badge aspect ratio and re-encode, EXIF and GPS data are dropped
+	processed, err := imaging.Normalize(src, photoOptions)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Фото слишком большое, допустимо до %d мегапикселей", imaging.MaxPixels/1_000_000))
 	}
-	newFilename := fmt.Sprintf("%s%s", memberID.String(), strings.ToLower(originalExt))
-	fullPath := filepath.Join(memberPhotoDir, newFilename) // Use path from config
-
-	// 8. Delete Old File (if exists) before saving new one
-	if member.PhotoFilename != "" && member.PhotoFilename != newFilename {
-		oldPath := filepath.Join(memberPhotoDir, member.PhotoFilename)
-		if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
-			// Log error but continue, maybe the old file was already gone
-			log.Printf("Warning: Failed to delete old photo '%s': %v", oldPath, err)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Не удалось обработать фото")
+	}
+	if photoFaceDetector != nil {
+		hasFace, err := photoFaceDetector.HasFace(processed.Image)
+		if err != nil {
+			logger.FromEcho(c).Error("face check failed", "member_id", member.ID, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Не удалось проверить фото")
+		}
+		if !hasFace {
+			return echo.NewHTTPError(http.StatusBadRequest, "На фото не найдено лицо")
 		}
 	}
 
-	// 9. Save New File
-	dst, err := os.Create(fullPath)
+	newFilename := member.ID.String() + ".jpg"
+	fullPath := filepath.Join(memberPhotoDir, newFilename)
+	if err := os.WriteFile(fullPath, processed.Photo, 0644); err != nil {
+		logger.FromEcho(c).Error("failed to save photo file", "path", fullPath, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Не удалось сохранить фото")
+	}
+	// a thumbnail of the previous photo must not outlive it, the feed rebuilds a missing one from the photo
+	err = os.MkdirAll(filepath.Join(memberPhotoDir, thumbnailDir), 0755)
+	if err == nil {
+		err = os.WriteFile(thumbnailPath(newFilename), processed.Thumbnail, 0644)
+	}
 	if err != nil {
-		log.Printf("Error creating destination file '%s': %v", fullPath, err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save file")
+		logger.FromEcho(c).Warn("failed to save photo thumbnail", "member_id", member.ID, "error", err)
+		os.Remove(thumbnailPath(newFilename))
 	}
-	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		log.Printf("Error copying file to '%s': %v", fullPath, err)
-		// Attempt to clean up partially written file
-		os.Remove(fullPath)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to copy file content")
+	// Delete the old file when the name changed, e.g. png photos uploaded before normalization
+	if member.PhotoFilename != "" && member.PhotoFilename != newFilename {
+		oldPath := filepath.Join(memberPhotoDir, member.PhotoFilename)
+		if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
+			logger.FromEcho(c).Warn("failed to delete old photo", "path", oldPath, "error", err)
+		}
 	}
 
-	// 10. Update Database
-	if err := db.Model(&member).Update("PhotoFilename", newFilename).Error; err != nil {
-		log.Printf("Error saving member photo filename %s to DB: %v", memberID, err)
-		// Attempt to clean up the newly saved file as the DB update failed
+	if err := db.Model(member).Update("PhotoFilename", newFilename).Error; err != nil {
+		logger.FromEcho(c).Error("failed to save member photo filename", "member_id", member.ID, "error", err)
 		os.Remove(fullPath)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update member record")
+		return echo.NewHTTPError(http.StatusInternalServerError, "Не удалось сохранить фото участника")
 	}
+	return nil
+}
 
-	// 11. Return Success
-	log.Printf("Successfully uploaded photo for member %s to %s", memberID, fullPath)
-	// Return the updated member object or just a success message
-	return c.JSON(http.StatusOK, member) // Return updated member
+// thumbnailPath is the path of the thumbnail of a photo file
+func thumbnailPath(filename string) string {
+	return filepath.Join(memberPhotoDir, thumbnailDir, strings.TrimSuffix(filename, filepath.Ext(filename))+".jpg")
 }
 
 // ServeMemberPhoto handles GET /:id/photo
@@ -177,12 +199,18 @@ func serveMemberPhoto(c echo.Context) error {
 	}
 
 	fullPath := filepath.Join(memberPhotoDir, member.PhotoFilename)
+	// ?thumbnail=true serves the small photo for lists when it exists
+	if thumbnail, _ := strconv.ParseBool(c.QueryParam("thumbnail")); thumbnail {
+		if _, err := os.Stat(thumbnailPath(member.PhotoFilename)); err == nil {
+			fullPath = thumbnailPath(member.PhotoFilename)
+		}
+	}
 
 	// Check if file exists before serving
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
 		return echo.NewHTTPError(http.StatusNotFound, "Photo not found")
 	} else if err != nil {
-		log.Printf("Error accessing photo file '%s': %v", fullPath, err)
+		logger.FromEcho(c).Error("failed to access photo file", "path", fullPath, "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Error accessing file")
 	}
 
@@ -199,15 +227,3 @@ func getAllowedTypesString() string {
 	}
 	return strings.Join(keys, ", ")
 }
-
-// Basic fallback for extension based on detected MIME type
-func getExtensionFromMime(mime string) string {
-	switch mime {
-	case "image/jpeg":
-		return ".jpg"
-	case "image/png":
-		return ".png"
-	default:
-		return ""
-	}
-}
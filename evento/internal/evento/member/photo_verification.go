@@ -0,0 +1,140 @@
+package member
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/imaging"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// photoURLTTL is the lifetime of signed photo urls returned by check
+const photoURLTTL = 5 * time.Minute
+
+// maxOfflinePhotos caps one page of GET /offline/photos
+const maxOfflinePhotos = 500
+
+// thumbnailMutex keeps concurrent feeds from writing the same missing thumbnail
+var thumbnailMutex sync.Mutex
+
+// OfflinePhoto is a photo thumbnail (jpeg data url) for scanners working without network
+type OfflinePhoto struct {
+	ID    uuid.UUID `json:"id"`
+	Photo string    `json:"photo"`
+}
+
+// photoRequired reports whether the guard must compare the member with the photo at the gate
+func photoRequired(member model.Member, gate model.Gate) bool {
+	return member.Accreditation.RequirePhoto || gate.RequirePhoto
+}
+
+// signedPhotoURL is a public url of the member photo which expires after photoURLTTL
+func signedPhotoURL(member model.Member, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(photoURLTTL).Unix(), 10)
+	info, err := os.Stat(filepath.Join(memberPhotoDir, member.PhotoFilename))
+	if err != nil {
+		return ""
+	}
+	query := url.Values{"exp": {expires}, "sig": {photoSignature(member, info.ModTime(), expires)}}
+	return "/api/public/members/" + member.ID.String() + "/photo?" + query.Encode()
+}
+
+// photoSignature is bound to the modification time of the photo file, the file name stays the same
+// when the photo is replaced, so a new upload invalidates old urls
+func photoSignature(member model.Member, modTime time.Time, expires string) string {
+	mac := hmac.New(sha256.New, publicURLKey)
+	mac.Write([]byte("photo:" + member.ID.String() + ":" + member.PhotoFilename + ":" + strconv.FormatInt(modTime.UnixNano(), 10) + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// getPublicPhoto serves the member photo for signed urls returned by check
+func getPublicPhoto(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusNotFound, `Фото не найдено`)
+	}
+	expires := c.QueryParam("exp")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return c.String(http.StatusNotFound, `Фото не найдено`)
+	}
+	var member model.Member
+	if err := db.Select("id", "photo_filename").First(&member, id).Error; err != nil || member.PhotoFilename == "" {
+		return c.String(http.StatusNotFound, `Фото не найдено`)
+	}
+	fullPath := filepath.Join(memberPhotoDir, member.PhotoFilename)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return c.String(http.StatusNotFound, `Фото не найдено`)
+	}
+	if !hmac.Equal([]byte(c.QueryParam("sig")), []byte(photoSignature(member, info.ModTime(), expires))) {
+		return c.String(http.StatusNotFound, `Фото не найдено`)
+	}
+	c.Response().Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(photoURLTTL.Seconds())))
+	return c.File(fullPath)
+}
+
+// photoThumbnail returns the jpeg thumbnail of the member photo as a data url for the offline feed,
+// photos uploaded before thumbnails existed get their thumbnail written on first use
+func photoThumbnail(filename string) (string, error) {
+	if thumbnail, err := os.ReadFile(thumbnailPath(filename)); err == nil {
+		return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumbnail), nil
+	}
+	thumbnailMutex.Lock()
+	defer thumbnailMutex.Unlock()
+	file, err := os.Open(filepath.Join(memberPhotoDir, filename))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	photo, err := imaging.Decode(file)
+	if err != nil {
+		return "", err
+	}
+	thumbnail, err := imaging.EncodeJPEG(imaging.Thumbnail(photo, photoOptions.ThumbnailSize), photoOptions.Quality)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(memberPhotoDir, thumbnailDir), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(thumbnailPath(filename), thumbnail, 0644); err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumbnail), nil
+}
+
+// offlinePhotos handles GET /offline/photos?offset=&limit=, thumbnails of members with photos ordered by id,
+// at most maxOfflinePhotos per page
+func offlinePhotos(c echo.Context) error {
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > maxOfflinePhotos {
+		limit = maxOfflinePhotos
+	}
+	var members []model.Member
+	if err := db.Select("id", "photo_filename").Where("photo_filename <> ''").Order("id").
+		Offset(offset).Limit(limit).Find(&members).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	photos := make([]OfflinePhoto, 0, len(members))
+	for _, member := range members {
+		if thumbnail, err := photoThumbnail(member.PhotoFilename); err == nil {
+			photos = append(photos, OfflinePhoto{ID: member.ID, Photo: thumbnail})
+		}
+	}
+	return c.JSON(http.StatusOK, photos)
+}
@@ -0,0 +1,256 @@
+package member
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	maxPhotoArchiveSize    = 200 * 1024 * 1024 // 200 MB
+	maxPhotoArchiveEntries = 500
+)
+
+// PhotoArchiveMatch is a file of the archive stored as the photo of a member
+type PhotoArchiveMatch struct {
+	File     string    `json:"file"`
+	MemberID uuid.UUID `json:"member_id"`
+	FIO      string    `json:"fio"`
+}
+
+// PhotoArchiveAmbiguous is a file which fits several members
+type PhotoArchiveAmbiguous struct {
+	File      string      `json:"file"`
+	MemberIDs []uuid.UUID `json:"member_ids"`
+}
+
+// PhotoArchiveFailure is a matched file which did not pass validation
+type PhotoArchiveFailure struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// PhotoArchiveResult reports every file of the archive
+type PhotoArchiveResult struct {
+	Uploaded  []PhotoArchiveMatch     `json:"uploaded"`
+	Unmatched []string                `json:"unmatched"`
+	Ambiguous []PhotoArchiveAmbiguous `json:"ambiguous"`
+	Failed    []PhotoArchiveFailure   `json:"failed"`
+}
+
+// uploadMemberPhotosZip handles POST /api/members/photos-zip: files of the "archive" zip are matched to members
+// of companies the user manages by document number, barcode or full name in the file name, ?company_id= narrows the search
+func uploadMemberPhotosZip(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	file, err := c.FormFile("archive")
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Загрузите zip архив в поле archive")
+	}
+	if file.Size > maxPhotoArchiveSize {
+		return c.String(http.StatusBadRequest, fmt.Sprintf("Архив больше %d МБ", maxPhotoArchiveSize/1024/1024))
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	defer src.Close()
+	archive, err := zip.NewReader(src, file.Size)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Файл не является zip архивом")
+	}
+	if len(archive.File) > maxPhotoArchiveEntries {
+		return c.String(http.StatusBadRequest, fmt.Sprintf("В архиве больше %d файлов", maxPhotoArchiveEntries))
+	}
+
+	members, err := manageableMembers(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	matcher := newPhotoMatcher(members)
+
+	result := PhotoArchiveResult{Uploaded: []PhotoArchiveMatch{}, Unmatched: []string{}, Ambiguous: []PhotoArchiveAmbiguous{}, Failed: []PhotoArchiveFailure{}}
+	usedBy := map[uuid.UUID]string{}
+	for _, entry := range archive.File {
+		name := archiveEntryName(entry)
+		base := path.Base(name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		matched := matcher.match(strings.TrimSuffix(base, path.Ext(base)))
+		switch {
+		case len(matched) == 0:
+			result.Unmatched = append(result.Unmatched, name)
+			continue
+		case len(matched) > 1:
+			ids := make([]uuid.UUID, 0, len(matched))
+			for _, member := range matched {
+				ids = append(ids, member.ID)
+			}
+			result.Ambiguous = append(result.Ambiguous, PhotoArchiveAmbiguous{File: name, MemberIDs: ids})
+			continue
+		}
+		member := matched[0]
+		if previous, ok := usedBy[member.ID]; ok {
+			result.Failed = append(result.Failed, PhotoArchiveFailure{File: name, Error: "Фото участника уже загружено из файла " + previous})
+			continue
+		}
+		if entry.UncompressedSize64 > maxUploadSize {
+			result.Failed = append(result.Failed, PhotoArchiveFailure{File: name, Error: fmt.Sprintf("Размер файла больше %d МБ", maxUploadSize/1024/1024)})
+			continue
+		}
+		content, err := readArchiveEntry(entry)
+		if err != nil {
+			result.Failed = append(result.Failed, PhotoArchiveFailure{File: name, Error: err.Error()})
+			continue
+		}
+		if err := saveMemberPhoto(c, member, bytes.NewReader(content), int64(len(content))); err != nil {
+			message := err.Error()
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				message = fmt.Sprint(httpErr.Message)
+			}
+			result.Failed = append(result.Failed, PhotoArchiveFailure{File: name, Error: message})
+			continue
+		}
+		usedBy[member.ID] = name
+		result.Uploaded = append(result.Uploaded, PhotoArchiveMatch{File: name, MemberID: member.ID, FIO: memberFIO(*member)})
+	}
+	logger.FromEcho(c).Info("member photos uploaded from archive", "uploaded", len(result.Uploaded), "unmatched", len(result.Unmatched),
+		"ambiguous", len(result.Ambiguous), "failed", len(result.Failed))
+	return c.JSON(http.StatusOK, result)
+}
+
+// archiveEntryName is the file name of the entry, names without the utf-8 flag are decoded from cp866
+// which Windows archivers use for cyrillic names
+func archiveEntryName(entry *zip.File) string {
+	if !entry.NonUTF8 {
+		return entry.Name
+	}
+	name, err := charmap.CodePage866.NewDecoder().String(entry.Name)
+	if err != nil {
+		return entry.Name
+	}
+	return name
+}
+
+// readArchiveEntry reads a file of the archive, the declared size is not trusted
+func readArchiveEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(io.LimitReader(reader, maxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxUploadSize {
+		return nil, fmt.Errorf("Размер файла больше %d МБ", maxUploadSize/1024/1024)
+	}
+	return content, nil
+}
+
+// manageableMembers loads members of companies the user can manage
+func manageableMembers(c echo.Context) ([]model.Member, error) {
+	query := db.Preload("User")
+	if raw := c.QueryParam("company_id"); raw != "" {
+		companyID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Неверный company_id")
+		}
+		query = query.Where("id = ?", companyID)
+	}
+	var companies []model.Company
+	if err := query.Find(&companies).Error; err != nil {
+		return nil, err
+	}
+	var companyIDs []uuid.UUID
+	for _, company := range companies {
+		if utils.CheckCompanyManagePermission(c, company) {
+			companyIDs = append(companyIDs, company.ID)
+		}
+	}
+	if len(companyIDs) == 0 {
+		return nil, fmt.Errorf("У вас недостаточно прав, чтобы сделать данный запрос")
+	}
+	var members []model.Member
+	err := db.Select("id", "company_id", "document", "barcode", "surname", "name", "middlename", "photo_filename").
+		Where("company_id IN ?", companyIDs).Find(&members).Error
+	return members, err
+}
+
+// photoMatcher finds members by a file name
+type photoMatcher struct {
+	byDocument map[string][]*model.Member
+	byBarcode  map[string][]*model.Member
+	byName     map[string][]*model.Member
+}
+
+func newPhotoMatcher(members []model.Member) photoMatcher {
+	matcher := photoMatcher{byDocument: map[string][]*model.Member{}, byBarcode: map[string][]*model.Member{}, byName: map[string][]*model.Member{}}
+	for i := range members {
+		member := &members[i]
+		if key := documentMatchKey(member.Document); key != "" {
+			matcher.byDocument[key] = append(matcher.byDocument[key], member)
+		}
+		if key := nameMatchKey(member.Barcode); key != "" {
+			matcher.byBarcode[key] = append(matcher.byBarcode[key], member)
+		}
+		fullName := nameMatchKey(member.Surname + " " + member.Name + " " + member.Middlename)
+		shortName := nameMatchKey(member.Surname + " " + member.Name)
+		if fullName != "" {
+			matcher.byName[fullName] = append(matcher.byName[fullName], member)
+		}
+		if shortName != "" && shortName != fullName {
+			matcher.byName[shortName] = append(matcher.byName[shortName], member)
+		}
+	}
+	return matcher
+}
+
+// match returns every member the file name fits, exactly one member means a match
+func (m photoMatcher) match(filename string) []*model.Member {
+	seen := map[uuid.UUID]bool{}
+	var matched []*model.Member
+	lists := [][]*model.Member{m.byDocument[documentMatchKey(filename)], m.byBarcode[nameMatchKey(filename)], m.byName[nameMatchKey(filename)]}
+	for _, list := range lists {
+		for _, member := range list {
+			if !seen[member.ID] {
+				seen[member.ID] = true
+				matched = append(matched, member)
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID.String() < matched[j].ID.String() })
+	return matched
+}
+
+// nameMatchKey ignores case, ё, underscores and repeated spaces: "Иванов_Пётр" matches "иванов петр"
+func nameMatchKey(value string) string {
+	value = strings.ToLower(strings.ReplaceAll(value, "_", " "))
+	value = strings.ReplaceAll(value, "ё", "е")
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// documentMatchKey also drops spaces and dashes inside document numbers
+func documentMatchKey(value string) string {
+	value = nameMatchKey(value)
+	return strings.NewReplacer(" ", "", "-", "").Replace(value)
+}
+
+func memberFIO(member model.Member) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", member.Surname, member.Name, member.Middlename))
+}
@@ -0,0 +1,331 @@
+package member
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// printOverride lets a supervisor print over the company in-event limit,
+// admins override with a reason only, operators need an approval issued by an admin
+type printOverride struct {
+	Token  string `json:"token"`
+	Reason string `json:"reason"`
+}
+
+// printOptions describe where and why badges are printed, Reason is required for reprints
+type printOptions struct {
+	Device     string         `json:"device"`
+	Printer    string         `json:"printer"`
+	TemplateID *uuid.UUID     `json:"template_id"`
+	Reason     string         `json:"reason"`
+	Override   *printOverride `json:"override"`
+}
+
+// MemberIDs ...
+type MemberIDs struct {
+	MemberIDs []uuid.UUID `json:"memberIds"`
+	printOptions
+}
+
+type printResult struct {
+	MemberID   string `json:"member_id"`
+	Status     string `json:"status"`
+	PrintCount uint   `json:"print_count,omitempty"`
+	Overridden bool   `json:"overridden,omitempty"`
+	Barcode    string `json:"barcode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type massPrintResponse struct {
+	PrintJobID uuid.UUID     `json:"print_job_id"`
+	Total      int           `json:"total"`
+	Printed    int           `json:"printed"`
+	Rejected   int           `json:"rejected"`
+	Results    []printResult `json:"results"`
+}
+
+// Print outcomes of a member
+const (
+	printStatusPrinted  = "printed"
+	printStatusRejected = "rejected"
+	printStatusNotFound = "not_found"
+	printStatusFailed   = "failed"
+)
+
+// Reprint reasons, a reprint for loss replaces the member barcode
+const (
+	reprintLost        = "lost"
+	reprintDamaged     = "damaged"
+	reprintDataChanged = "data_changed"
+)
+
+var reprintReasons = map[string]bool{reprintLost: true, reprintDamaged: true, reprintDataChanged: true}
+
+// printLimitError rejects the first print of a member when the company in-event limit is used up
+type printLimitError struct {
+	Printed uint
+	Limit   uint
+}
+
+func (e *printLimitError) Error() string {
+	return fmt.Sprintf("Превышен лимит единовременного присутствия компании (напечатано %d из %d), требуется разрешение руководителя", e.Printed, e.Limit)
+}
+
+var errReprintReason = errors.New("Укажите причину повторной печати: lost, damaged или data_changed")
+
+// print member
+func print(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, `{"error":"invalid id"}`)
+	}
+	var options printOptions
+	if err := c.Bind(&options); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	job, supervisor, err := startPrintJob(c, &options, 1)
+	if err != nil {
+		return printJobError(c, err)
+	}
+	member, overridden, err := printMember(c, id, options, job.ID, supervisor)
+	finishPrintJob(c, job, err == nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusNotFound, `{"error":"member is not found"}`)
+		}
+		var limitErr *printLimitError
+		if errors.As(err, &limitErr) {
+			return c.String(http.StatusConflict, limitErr.Error())
+		}
+		if errors.Is(err, errReprintReason) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, errPrintOverrideDenied) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if overridden {
+		c.Response().Header().Set("X-Print-Override", "true")
+	}
+	c.Response().Header().Set("X-Print-Job", job.ID.String())
+	return c.JSON(http.StatusOK, member)
+}
+
+// massPrint members as one print job, every member gets its own outcome
+func massPrint(c echo.Context) error {
+	var ids MemberIDs
+	if err := c.Bind(&ids); err != nil {
+		return c.String(http.StatusBadRequest, `{"error":"invalid request body"}`)
+	}
+	job, supervisor, err := startPrintJob(c, &ids.printOptions, len(ids.MemberIDs))
+	if err != nil {
+		return printJobError(c, err)
+	}
+	response := massPrintResponse{PrintJobID: job.ID, Total: len(ids.MemberIDs), Results: make([]printResult, 0, len(ids.MemberIDs))}
+	for _, id := range ids.MemberIDs {
+		result := printResult{MemberID: id.String()}
+		member, overridden, err := printMember(c, id, ids.printOptions, job.ID, supervisor)
+		finishPrintJob(c, job, err == nil)
+		var limitErr *printLimitError
+		switch {
+		case err == nil:
+			result.Status = printStatusPrinted
+			result.PrintCount = member.PrintCount
+			result.Overridden = overridden
+			result.Barcode = member.Barcode
+			response.Printed++
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			result.Status = printStatusNotFound
+			result.Error = "Участник не найден"
+			response.Rejected++
+		case errors.As(err, &limitErr), errors.Is(err, errReprintReason), errors.Is(err, errPrintOverrideDenied):
+			result.Status = printStatusRejected
+			result.Error = err.Error()
+			response.Rejected++
+		default:
+			result.Status = printStatusFailed
+			result.Error = err.Error()
+			response.Rejected++
+		}
+		response.Results = append(response.Results, result)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// startPrintJob validates print options and records the job of the operator
+func startPrintJob(c echo.Context, options *printOptions, total int) (*model.PrintJob, printSupervisor, error) {
+	options.Device = strings.TrimSpace(options.Device)
+	options.Printer = strings.TrimSpace(options.Printer)
+	options.Reason = strings.TrimSpace(options.Reason)
+	if options.Reason != "" && !reprintReasons[options.Reason] {
+		return nil, printSupervisor{}, errReprintReason
+	}
+	if options.TemplateID != nil {
+		var count int64
+		if err := db.Model(&model.BadgeTemplate{}).Where("id = ?", *options.TemplateID).Count(&count).Error; err != nil || count == 0 {
+			return nil, printSupervisor{}, errors.New("Шаблон бейджа не найден")
+		}
+	}
+	supervisor, err := authorizePrintOverride(c, options.Override)
+	if err != nil {
+		return nil, supervisor, err
+	}
+	operatorID, _ := utils.GetUser(c)
+	job := model.PrintJob{
+		OperatorID: operatorID,
+		Device:     options.Device,
+		Printer:    options.Printer,
+		TemplateID: options.TemplateID,
+		Reason:     options.Reason,
+		Total:      total,
+	}
+	if err := db.Create(&job).Error; err != nil {
+		return nil, supervisor, err
+	}
+	return &job, supervisor, nil
+}
+
+// printJobError answers a rejected print job, a denied supervisor approval is forbidden
+func printJobError(c echo.Context, err error) error {
+	if errors.Is(err, errPrintOverrideDenied) {
+		return c.String(http.StatusForbidden, err.Error())
+	}
+	return c.String(http.StatusBadRequest, err.Error())
+}
+
+// finishPrintJob counts the outcome of one member of the job
+// the print itself is already stored, so a failed count is only logged
+func finishPrintJob(c echo.Context, job *model.PrintJob, printed bool) {
+	column := "rejected"
+	if printed {
+		column = "printed"
+	}
+	if err := db.Model(&model.PrintJob{}).Where("id = ?", job.ID).Update(column, gorm.Expr(column+" + 1")).Error; err != nil {
+		logger.FromEcho(c).Error("unable to count print job outcome", "print_job_id", job.ID, "printed", printed, "error", err)
+	}
+}
+
+// printMember counts a print of the member. The first print takes a place of the company
+// in-event limit, over the limit it needs an authorized override which is logged to member history.
+// A reprint needs a reason, a reprint for loss invalidates the old barcode
+func printMember(c echo.Context, id uuid.UUID, options printOptions, jobID uuid.UUID, supervisor printSupervisor) (model.Member, bool, error) {
+	var member model.Member
+	if err := db.Select("id", "company_id").First(&member, id).Error; err != nil {
+		return member, false, err
+	}
+	unlock := lockMemberLimits(db, member.CompanyID)
+	defer unlock()
+
+	operatorID, _ := utils.GetUser(c)
+	memberPrint := model.MemberPrint{
+		MemberID:   id,
+		PrintJobID: jobID,
+		OperatorID: operatorID,
+		Device:     options.Device,
+		Printer:    options.Printer,
+		TemplateID: options.TemplateID,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&member, id).Error; err != nil {
+			return err
+		}
+		if member.PrintCount > 0 {
+			if options.Reason == "" {
+				return errReprintReason
+			}
+			memberPrint.Reprint = true
+			memberPrint.Reason = options.Reason
+		} else {
+			limit, printed, err := companyPrintQuota(tx, member.CompanyID)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && printed >= limit {
+				if options.Override == nil {
+					return &printLimitError{Printed: printed, Limit: limit}
+				}
+				if err := usePrintOverride(tx, supervisor, member.CompanyID); err != nil {
+					return err
+				}
+				memberPrint.Overridden = true
+				details, _ := json.Marshal(map[string]interface{}{
+					"supervisor_id": supervisor.ID,
+					"approval_id":   supervisor.ApprovalID,
+					"reason":        options.Override.Reason,
+					"printed":       printed,
+					"limit":         limit,
+					"print_job_id":  jobID,
+				})
+				if err := logMemberHistory(tx, c, member.ID, "print_override", string(details)); err != nil {
+					return err
+				}
+			}
+		}
+		if memberPrint.Reason == reprintLost {
+			barcode, err := generateUniqueMemberBarcode(tx)
+			if err != nil {
+				return err
+			}
+			memberPrint.InvalidatedBarcode = member.Barcode
+			if err := replaceMemberBarcode(tx, member, barcode, barcodeRevokedLost); err != nil {
+				return err
+			}
+			member.Barcode = barcode
+			details, _ := json.Marshal(map[string]interface{}{
+				"old_barcode":  memberPrint.InvalidatedBarcode,
+				"new_barcode":  barcode,
+				"reason":       reprintLost,
+				"print_job_id": jobID,
+			})
+			if err := logMemberHistory(tx, c, member.ID, "barcode_invalidated", string(details)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.Member{}).Where("id = ?", member.ID).Update("print_count", gorm.Expr("print_count + 1")).Error; err != nil {
+			return err
+		}
+		member.PrintCount++
+		return tx.Create(&memberPrint).Error
+	})
+	return member, memberPrint.Overridden, err
+}
+
+// companyPrintQuota returns the in-event limit of the company and how many of its members are printed
+func companyPrintQuota(tx *gorm.DB, companyID uuid.UUID) (uint, uint, error) {
+	var company model.Company
+	if err := tx.Select("id", "in_event_members_limit").First(&company, companyID).Error; err != nil {
+		return 0, 0, err
+	}
+	var printed int64
+	if err := tx.Model(&model.Member{}).Where("company_id = ? AND print_count > 0", companyID).Count(&printed).Error; err != nil {
+		return 0, 0, err
+	}
+	return company.InEventMembersLimit, uint(printed), nil
+}
+
+// authorizePrintOverride checks who allows the override, nil override is no override
+func authorizePrintOverride(c echo.Context, override *printOverride) (printSupervisor, error) {
+	if override == nil {
+		return printSupervisor{}, nil
+	}
+	override.Reason = strings.TrimSpace(override.Reason)
+	if override.Reason == "" {
+		return printSupervisor{}, errors.New("Укажите причину печати сверх лимита")
+	}
+	userID, role := utils.GetUser(c)
+	if role == "admin" && override.Token == "" {
+		return printSupervisor{ID: userID}, nil
+	}
+	return verifyPrintOverride(strings.TrimSpace(override.Token))
+}
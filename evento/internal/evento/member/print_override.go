@@ -0,0 +1,120 @@
+package member
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// printOverrideTTL is the lifetime of supervisor approvals for printing over the limit
+const printOverrideTTL = 10 * time.Minute
+
+// maxPrintOverrideCount caps how many badges one approval allows
+const maxPrintOverrideCount = 100
+
+// printOverrideKey signs supervisor approvals, derived from the jwt signing key
+var printOverrideKey []byte
+
+var errPrintOverrideDenied = errors.New("Разрешение руководителя недействительно, исчерпано, истекло или выдано для другой компании")
+
+// printSupervisor is who allows printing over the limit, ApprovalID is uuid.Nil when an admin prints without an approval
+type printSupervisor struct {
+	ID         uuid.UUID
+	ApprovalID uuid.UUID
+}
+
+// issuePrintOverride gives the admin a short-lived approval the operator passes as override.token,
+// it allows count first prints of members of the company over its in-event limit
+func issuePrintOverride(c echo.Context) error {
+	var input struct {
+		CompanyID uuid.UUID `json:"company_id"`
+		Count     uint      `json:"count"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if input.Count == 0 {
+		input.Count = 1
+	}
+	if input.Count > maxPrintOverrideCount {
+		return c.String(http.StatusBadRequest, "Разрешение выдается не более чем на 100 бейджей")
+	}
+	var company model.Company
+	if err := db.Select("id").First(&company, input.CompanyID).Error; err != nil {
+		return c.String(http.StatusBadRequest, "Компания не найдена")
+	}
+	userID, _ := utils.GetUser(c)
+	approval := model.PrintOverrideApproval{
+		AdminID:   userID,
+		CompanyID: company.ID,
+		MaxCount:  input.Count,
+		ExpiresAt: time.Now().Add(printOverrideTTL).UTC().Truncate(time.Second),
+	}
+	if err := db.Create(&approval).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"token":      approval.ID.String() + "." + printOverrideSignature(approval.ID),
+		"company_id": approval.CompanyID,
+		"count":      approval.MaxCount,
+		"expires_at": approval.ExpiresAt,
+	})
+}
+
+func printOverrideSignature(approvalID uuid.UUID) string {
+	mac := hmac.New(sha256.New, printOverrideKey)
+	mac.Write([]byte("print-override:" + approvalID.String()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// verifyPrintOverride checks the "<approval id>.<signature>" token, the approval must have prints left
+// and the admin who issued it must still be an admin
+func verifyPrintOverride(token string) (printSupervisor, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return printSupervisor{}, errPrintOverrideDenied
+	}
+	approvalID, err := uuid.Parse(parts[0])
+	if err != nil || !hmac.Equal([]byte(parts[1]), []byte(printOverrideSignature(approvalID))) {
+		return printSupervisor{}, errPrintOverrideDenied
+	}
+	var approval model.PrintOverrideApproval
+	if err := db.First(&approval, approvalID).Error; err != nil {
+		return printSupervisor{}, errPrintOverrideDenied
+	}
+	if !time.Now().Before(approval.ExpiresAt) || approval.Used >= approval.MaxCount {
+		return printSupervisor{}, errPrintOverrideDenied
+	}
+	var count int64
+	if err := db.Model(&model.User{}).Where("id = ? AND role = ?", approval.AdminID, "admin").Count(&count).Error; err != nil || count == 0 {
+		return printSupervisor{}, errPrintOverrideDenied
+	}
+	return printSupervisor{ID: approval.AdminID, ApprovalID: approval.ID}, nil
+}
+
+// usePrintOverride takes one print of the approval for a member of the company
+func usePrintOverride(tx *gorm.DB, supervisor printSupervisor, companyID uuid.UUID) error {
+	if supervisor.ApprovalID == uuid.Nil {
+		return nil
+	}
+	result := tx.Model(&model.PrintOverrideApproval{}).
+		Where("id = ? AND company_id = ? AND used < max_count AND expires_at > ?", supervisor.ApprovalID, companyID, time.Now()).
+		Update("used", gorm.Expr("used + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errPrintOverrideDenied
+	}
+	return nil
+}
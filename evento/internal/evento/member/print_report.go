@@ -0,0 +1,126 @@
+package member
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type printReportRow struct {
+	MemberID           uuid.UUID  `json:"member_id"`
+	MemberName         string     `json:"member_name"`
+	PrintJobID         uuid.UUID  `json:"print_job_id"`
+	PrintedAt          time.Time  `json:"printed_at"`
+	Operator           string     `json:"operator"`
+	Device             string     `json:"device"`
+	Printer            string     `json:"printer"`
+	TemplateID         *uuid.UUID `json:"template_id"`
+	Reprint            bool       `json:"reprint"`
+	Reason             string     `json:"reason"`
+	Overridden         bool       `json:"overridden"`
+	InvalidatedBarcode string     `json:"invalidated_barcode,omitempty"`
+}
+
+type printReport struct {
+	CompanyID           uuid.UUID        `json:"company_id"`
+	CompanyName         string           `json:"company_name"`
+	InEventMembersLimit uint             `json:"in_event_members_limit"`
+	MembersPrinted      uint             `json:"members_printed"`
+	Prints              int              `json:"prints"`
+	FirstPrints         int              `json:"first_prints"`
+	Reprints            int              `json:"reprints"`
+	ReprintsByReason    map[string]int   `json:"reprints_by_reason"`
+	Overrides           int              `json:"overrides"`
+	InvalidatedBarcodes int              `json:"invalidated_barcodes"`
+	Rows                []printReportRow `json:"rows"`
+}
+
+// getPrintReport lists prints of the company with reprint reasons and totals
+func getPrintReport(c echo.Context) error {
+	companyID, err := utils.ResolveCompanyIDForManage(c, db, c.QueryParam("company_id"))
+	if err != nil {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			return c.String(httpErr.Code, fmt.Sprint(httpErr.Message))
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	var company model.Company
+	if err := db.First(&company, companyID).Error; err != nil {
+		return c.String(http.StatusNotFound, `Компания не найдена`)
+	}
+	limit, printed, err := companyPrintQuota(db, companyID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	report := printReport{
+		CompanyID:           company.ID,
+		CompanyName:         company.Name,
+		InEventMembersLimit: limit,
+		MembersPrinted:      printed,
+		ReprintsByReason:    map[string]int{},
+		Rows:                []printReportRow{},
+	}
+	err = db.Table("member_prints").
+		Select(`member_prints.member_id, TRIM(members.surname || ' ' || members.name || ' ' || members.middlename) AS member_name,
+			member_prints.print_job_id, member_prints.created_at AS printed_at, COALESCE(users.username, '') AS operator,
+			member_prints.device, member_prints.printer, member_prints.template_id, member_prints.reprint,
+			member_prints.reason, member_prints.overridden, member_prints.invalidated_barcode`).
+		Joins("JOIN members ON members.id = member_prints.member_id").
+		Joins("LEFT JOIN users ON users.id = member_prints.operator_id").
+		Where("members.company_id = ? AND member_prints.deleted_at IS NULL", companyID).
+		Order("member_prints.created_at DESC").
+		Scan(&report.Rows).Error
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	for _, row := range report.Rows {
+		report.Prints++
+		if row.Reprint {
+			report.Reprints++
+			report.ReprintsByReason[row.Reason]++
+		} else {
+			report.FirstPrints++
+		}
+		if row.Overridden {
+			report.Overrides++
+		}
+		if row.InvalidatedBarcode != "" {
+			report.InvalidatedBarcodes++
+		}
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func getPrintJobs(c echo.Context) error {
+	query := db.Order("created_at DESC").Limit(parsePositiveInt(c.QueryParam("limit"), 100))
+	if operatorID := c.QueryParam("operator_id"); operatorID != "" {
+		query = query.Where("operator_id = ?", operatorID)
+	}
+	var jobs []model.PrintJob
+	if err := query.Find(&jobs).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, jobs)
+}
+
+func getPrintJob(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, `{"error":"invalid id"}`)
+	}
+	var job model.PrintJob
+	if err := db.Preload("Prints").First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusNotFound, `Задание печати не найдено`)
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, job)
+}
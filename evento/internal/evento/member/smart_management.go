@@ -247,6 +247,14 @@ func updateSmartManagement(c echo.Context) error {
 			continue
 		}
 
+		var companyID uuid.UUID
+		if err := db.Model(&model.Member{}).Where("id = ?", update.MemberID).Pluck("company_id", &companyID).Error; err != nil {
+			result.Error = err.Error()
+			response.Failed++
+			response.Results = append(response.Results, result)
+			continue
+		}
+		unlock := lockMemberLimits(db, companyID)
 		err := db.Transaction(func(tx *gorm.DB) error {
 			var member model.Member
 			if err := tx.Preload("Gates").First(&member, update.MemberID).Error; err != nil {
@@ -308,6 +316,7 @@ func updateSmartManagement(c echo.Context) error {
 
 			return nil
 		})
+		unlock()
 
 		if err != nil {
 			result.Error = err.Error()
@@ -1,6 +1,8 @@
 package report
 
 import (
+	"sync"
+
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
 	echojwt "github.com/labstack/echo-jwt"
@@ -10,15 +12,30 @@ import (
 
 var db *gorm.DB
 var dashboardSettings model.ReportDashboardSettings
+var dashboardSettingsMu sync.RWMutex
 
 // InitReports entry point of reports
 func InitReports(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config, reportDashboardSettings model.ReportDashboardSettings) {
 	db = dbInstance
-	dashboardSettings = reportDashboardSettings
+	SetDashboardSettings(reportDashboardSettings)
 	g.Use(echojwt.WithConfig(jwtConfig))
 	g.GET("/users", allUsers, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("/autos", allAutos, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("/members", allMembers, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("/companies", allCompanies, utils.RoleMiddleware([]string{"admin"}))
 	g.GET("/dashboard", dashboard, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/capacity", capacityReport, utils.RoleMiddleware([]string{"admin", "editor"}))
+}
+
+// SetDashboardSettings replaces dashboard thresholds, used on config reload
+func SetDashboardSettings(settings model.ReportDashboardSettings) {
+	dashboardSettingsMu.Lock()
+	dashboardSettings = settings
+	dashboardSettingsMu.Unlock()
+}
+
+func currentDashboardSettings() model.ReportDashboardSettings {
+	dashboardSettingsMu.RLock()
+	defer dashboardSettingsMu.RUnlock()
+	return dashboardSettings
 }
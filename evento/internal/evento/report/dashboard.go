@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
 
@@ -59,17 +60,19 @@ type passActivity struct {
 }
 
 type dashboardResponse struct {
-	GeneratedAt         time.Time           `json:"generatedAt"`
-	Config              dashboardConfig     `json:"config"`
-	Summary             dashboardSummary    `json:"summary"`
-	MembersByAccred     []namedCount        `json:"membersByAccreditation"`
-	PassesByGate        []namedCount        `json:"passesByGate"`
-	CompaniesByLimits   []companyLimitStats `json:"companiesByLimits"`
-	TopPassActivity     []passActivity      `json:"topPassActivity"`
-	PassesWindowStarted time.Time           `json:"passesWindowStarted"`
+	GeneratedAt         time.Time             `json:"generatedAt"`
+	Config              dashboardConfig       `json:"config"`
+	Summary             dashboardSummary      `json:"summary"`
+	MembersByAccred     []namedCount          `json:"membersByAccreditation"`
+	PassesByGate        []namedCount          `json:"passesByGate"`
+	CompaniesByLimits   []companyLimitStats   `json:"companiesByLimits"`
+	TopPassActivity     []passActivity        `json:"topPassActivity"`
+	PassesWindowStarted time.Time             `json:"passesWindowStarted"`
+	Capacity            []utils.CapacityUsage `json:"capacity"`
 }
 
 func dashboard(c echo.Context) error {
+	dashboardSettings := currentDashboardSettings()
 	passWindowMinutes := dashboardSettings.PassWindowMinutes
 	topItemsLimit := dashboardSettings.TopItemsLimit
 	anomalyThreshold := dashboardSettings.AnomalyThreshold
@@ -200,5 +203,20 @@ func dashboard(c echo.Context) error {
 		LIMIT ?
 	`, passesWindowStart, anomalyThreshold, topItemsLimit).Scan(&response.TopPassActivity)
 
+	capacity, err := utils.CapacityUsages(db)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	response.Capacity = capacity
+
 	return c.JSON(http.StatusOK, response)
 }
+
+// capacityReport shows allocated company limits and registered members against the physical capacity
+func capacityReport(c echo.Context) error {
+	capacity, err := utils.CapacityUsages(db)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, capacity)
+}
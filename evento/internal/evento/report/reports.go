@@ -2,8 +2,10 @@ package report
 
 import (
 	"bytes"
-	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/eugenetolok/evento/pkg/model"
@@ -13,14 +15,73 @@ import (
 	"gorm.io/gorm"
 )
 
+// reportBuilders maps report names to their xlsx builders
+var reportBuilders = map[string]func(database *gorm.DB) (*xlsx.File, error){
+	"autos":     buildAutosReport,
+	"users":     buildUsersReport,
+	"companies": buildCompaniesReport,
+	"members":   buildMembersReport,
+}
+
+// ReportNames returns the names accepted by Export
+func ReportNames() []string {
+	names := make([]string, 0, len(reportBuilders))
+	for name := range reportBuilders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export writes the named xlsx report to w
+func Export(database *gorm.DB, name string, w io.Writer) error {
+	build, ok := reportBuilders[name]
+	if !ok {
+		return fmt.Errorf("unknown report %q", name)
+	}
+	file, err := build(database)
+	if err != nil {
+		return err
+	}
+	return file.Write(w)
+}
+
 func allAutos(c echo.Context) error {
+	return sendReport(c, "autos")
+}
+
+func allUsers(c echo.Context) error {
+	return sendReport(c, "users")
+}
+
+func allCompanies(c echo.Context) error {
+	return sendReport(c, "companies")
+}
+
+func allMembers(c echo.Context) error {
+	return sendReport(c, "members")
+}
+
+func sendReport(c echo.Context, name string) error {
+	// Save the file as a byte array
+	buffer := new(bytes.Buffer)
+	if err := Export(db, name, buffer); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	// Set the response headers for file download
+	c.Response().Header().Set(echo.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=members_report.xlsx")
+
+	// Return the file content as a response
+	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buffer.Bytes())
+}
+
+func buildAutosReport(database *gorm.DB) (*xlsx.File, error) {
 	// Fetch the company data
 	var autos []model.Auto
-	if err := db.Find(&autos).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.String(http.StatusNotFound, `{"error":"company not found"}`)
-		}
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Find(&autos).Error; err != nil {
+		return nil, err
 	}
 
 	// Create a new Excel file
@@ -50,26 +111,14 @@ func allAutos(c echo.Context) error {
 		addCell(row, auto.Company)
 	}
 
-	// Save the file as a byte array
-	buffer := new(bytes.Buffer)
-	file.Write(buffer)
-
-	// Set the response headers for file download
-	c.Response().Header().Set(echo.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=members_report.xlsx")
-
-	// Return the file content as a response
-	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buffer.Bytes())
+	return file, nil
 }
 
-func allUsers(c echo.Context) error {
+func buildUsersReport(database *gorm.DB) (*xlsx.File, error) {
 	// Fetch the company data
 	var users []model.User
-	if err := db.Find(&users).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.String(http.StatusNotFound, `{"error":"company not found"}`)
-		}
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Find(&users).Error; err != nil {
+		return nil, err
 	}
 
 	// Create a new Excel file
@@ -95,39 +144,27 @@ func allUsers(c echo.Context) error {
 		addCell(row, user.Role)
 	}
 
-	// Save the file as a byte array
-	buffer := new(bytes.Buffer)
-	file.Write(buffer)
-
-	// Set the response headers for file download
-	c.Response().Header().Set(echo.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=members_report.xlsx")
-
-	// Return the file content as a response
-	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buffer.Bytes())
+	return file, nil
 }
 
-func allCompanies(c echo.Context) error {
+func buildCompaniesReport(database *gorm.DB) (*xlsx.File, error) {
 	// Fetch the company data
 	var companies []model.Company
-	if err := db.Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").Find(&companies).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.String(http.StatusNotFound, `{"error":"company not found"}`)
-		}
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Preload("AccreditationLimits").Preload("EventLimits").Preload("GateLimits").Find(&companies).Error; err != nil {
+		return nil, err
 	}
 
 	var allAccreditations []model.Accreditation
-	if err := db.Order("position desc").Find(&allAccreditations).Error; err != nil {
-		return c.String(http.StatusInternalServerError, "Error fetching accreditations: "+err.Error())
+	if err := database.Order("position desc").Find(&allAccreditations).Error; err != nil {
+		return nil, fmt.Errorf("Error fetching accreditations: %w", err)
 	}
 	var allEvents []model.Event
-	if err := db.Order("position desc").Find(&allEvents).Error; err != nil {
-		return c.String(http.StatusInternalServerError, "Error fetching events: "+err.Error())
+	if err := database.Order("position desc").Find(&allEvents).Error; err != nil {
+		return nil, fmt.Errorf("Error fetching events: %w", err)
 	}
 	var allGates []model.Gate // Assuming all gates can have limits. Adjust if only 'additional' gates, etc.
-	if err := db.Order("position desc").Find(&allGates).Error; err != nil {
-		return c.String(http.StatusInternalServerError, "Error fetching gates: "+err.Error())
+	if err := database.Order("position desc").Find(&allGates).Error; err != nil {
+		return nil, fmt.Errorf("Error fetching gates: %w", err)
 	}
 
 	// Create a new Excel file
@@ -232,26 +269,14 @@ func allCompanies(c echo.Context) error {
 		}
 	}
 
-	// Save the file as a byte array
-	buffer := new(bytes.Buffer)
-	file.Write(buffer)
-
-	// Set the response headers for file download
-	c.Response().Header().Set(echo.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=members_report.xlsx")
-
-	// Return the file content as a response
-	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buffer.Bytes())
+	return file, nil
 }
 
-func allMembers(c echo.Context) error {
+func buildMembersReport(database *gorm.DB) (*xlsx.File, error) {
 	// Fetch the company data
 	var members []model.Member
-	if err := db.Order("company_name asc").Preload("Company").Preload("Accreditation").Preload("Events").Preload("Gates").Find(&members).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.String(http.StatusNotFound, `{"error":"company not found"}`)
-		}
-		return c.String(http.StatusInternalServerError, err.Error())
+	if err := database.Order("company_name asc").Preload("Company").Preload("Accreditation").Preload("Events").Preload("Gates").Find(&members).Error; err != nil {
+		return nil, err
 	}
 
 	// Fetch limits
@@ -259,9 +284,9 @@ func allMembers(c echo.Context) error {
 	var companyEventLimits []model.CompanyEventLimit
 	var companyGateLimits []model.CompanyGateLimit
 
-	db.Find(&companyAccreditationLimits)
-	db.Find(&companyEventLimits)
-	db.Find(&companyGateLimits)
+	database.Find(&companyAccreditationLimits)
+	database.Find(&companyEventLimits)
+	database.Find(&companyGateLimits)
 
 	// Create a new Excel file
 	file := xlsx.NewFile()
@@ -302,13 +327,13 @@ func allMembers(c echo.Context) error {
 	addHeaderCell(row, "Создан", headerStyle)
 
 	var events []model.Event
-	db.Order("position desc").Find(&events)
+	database.Order("position desc").Find(&events)
 	for _, event := range events {
 		addHeaderCell(row, event.Name, eventHeaderStyle)
 	}
 
 	var gates []model.Gate
-	db.Order("position desc").Where("additional = ?", true).Find(&gates)
+	database.Order("position desc").Where("additional = ?", true).Find(&gates)
 	for _, gate := range gates {
 		addHeaderCell(row, gate.Name, gateHeaderStyle)
 	}
@@ -433,16 +458,7 @@ func allMembers(c echo.Context) error {
 		}
 	}
 
-	// Save the file as a byte array
-	buffer := new(bytes.Buffer)
-	file.Write(buffer)
-
-	// Set the response headers for file download
-	c.Response().Header().Set(echo.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=members_report.xlsx")
-
-	// Return the file content as a response
-	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buffer.Bytes())
+	return file, nil
 }
 
 // Helper functions to add cells
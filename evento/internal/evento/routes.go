@@ -1,27 +1,31 @@
 package evento
 
 import (
-	"log"
 	"net/http"
-	"time"
 
 	"github.com/eugenetolok/evento/internal/evento/accreditation"
 	"github.com/eugenetolok/evento/internal/evento/aiassistant"
 	"github.com/eugenetolok/evento/internal/evento/auto"
 	"github.com/eugenetolok/evento/internal/evento/badge"
+	"github.com/eugenetolok/evento/internal/evento/bangle"
 	"github.com/eugenetolok/evento/internal/evento/company"
+	"github.com/eugenetolok/evento/internal/evento/credential"
 	"github.com/eugenetolok/evento/internal/evento/emailtemplate"
 	"github.com/eugenetolok/evento/internal/evento/event"
+	"github.com/eugenetolok/evento/internal/evento/frontendsettings"
 	"github.com/eugenetolok/evento/internal/evento/gate"
 	"github.com/eugenetolok/evento/internal/evento/member"
 	"github.com/eugenetolok/evento/internal/evento/report"
+	"github.com/eugenetolok/evento/internal/evento/scheduledaction"
 	"github.com/eugenetolok/evento/internal/evento/user"
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/metrics"
 	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
 	"github.com/golang-jwt/jwt/v4"
 	echojwt "github.com/labstack/echo-jwt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"golang.org/x/time/rate"
 )
 
 func Routes(e *echo.Echo) {
@@ -32,16 +36,13 @@ func Routes(e *echo.Echo) {
 		SigningKey: []byte(appSettings.SiteSettings.SecretJWT),
 		ErrorHandler: func(c echo.Context, err error) error {
 			// Log the error or return a custom error message
-			log.Printf("JWT Error: %v", err)
+			logger.FromEcho(c).Debug("jwt rejected", "error", err)
 			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid or expired JWT")
 		},
 	}
+	authLimiterStore.configure(appSettings.SiteSettings.AuthRateLimitRPS, appSettings.SiteSettings.AuthRateLimitBurst)
 	authLimiter := middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
-		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
-			Rate:      rate.Limit(appSettings.SiteSettings.AuthRateLimitRPS),
-			Burst:     appSettings.SiteSettings.AuthRateLimitBurst,
-			ExpiresIn: 3 * time.Minute,
-		}),
+		Store: authLimiterStore,
 		IdentifierExtractor: func(c echo.Context) (string, error) {
 			return c.RealIP(), nil
 		},
@@ -52,7 +53,15 @@ func Routes(e *echo.Echo) {
 		},
 	})
 
+	e.GET("/healthz", healthz)
+	e.GET("/readyz", readyz)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()), metricsAuth)
 	e.GET("/api/settings/frontend", frontendConfig)
+	e.GET("/api/settings/effective", effectiveConfig, echojwt.WithConfig(jwtConfig), utils.RoleMiddleware([]string{"admin"}))
+	jobsGroup := e.Group("/api/jobs", echojwt.WithConfig(jwtConfig), utils.RoleMiddleware([]string{"admin"}))
+	jobsGroup.GET("", listJobs)
+	jobsGroup.GET("/:name", getJob)
+	jobsGroup.POST("/:name/run", runJob)
 	e.POST("/api/auth", authUser, authLimiter)
 	e.POST("/api/auth/reset-password", user.CompleteResetPassword, authLimiter)
 	// Restricted group
@@ -64,12 +73,17 @@ func Routes(e *echo.Echo) {
 	auto.InitAutos(e.Group("/api/autos"), db, jwtConfig)
 	user.InitUsers(e.Group("/api/users"), db, jwtConfig)
 	gate.InitGates(e.Group("/api/gates"), db, jwtConfig)
-	badge.InitBadges(e.Group("/api/badges"), db, jwtConfig)
+	badge.InitBadges(e.Group("/api/badges"), db, jwtConfig, photoStorageDir, baseFrontendSettings)
 	event.InitEvents(e.Group("/api/events"), db, jwtConfig)
 	report.InitReports(e.Group("/api/reports"), db, jwtConfig, appSettings.ReportSettings.Dashboard)
 	company.InitCompanies(e.Group("/api/companies"), db, jwtConfig)
-	member.InitMembers(e.Group("/api/members"), db, jwtConfig, photoStorageDir)
+	member.InitMembers(e.Group("/api/members"), db, jwtConfig, photoStorageDir, appSettings.BadgeSettings, appSettings.PhotoSettings)
+	member.InitPublicMembers(e.Group("/api/public/members"))
+	credential.InitCredentials(e.Group("/api/credentials"), db, jwtConfig)
+	bangle.InitBangles(e.Group("/api/bangles"), db, jwtConfig)
 	accreditation.InitAccreditations(e.Group("/api/accreditations"), db, jwtConfig)
 	emailtemplate.InitEmailTemplates(e.Group("/api/email-templates"), db, jwtConfig)
+	frontendsettings.InitFrontendSettings(e.Group("/api/frontend-settings"), db, jwtConfig, baseFrontendSettings)
+	scheduledaction.InitScheduledActions(e.Group("/api/scheduled-actions"), db, jwtConfig)
 	aiassistant.InitAIAssistant(e.Group("/api/ai-assistant"), jwtConfig, appSettings.AIAssistantSettings, appSettings.SiteSettings.DBPath)
 }
@@ -0,0 +1,385 @@
+package scheduledaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/internal/evento/report"
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/smtp"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Statuses of a scheduled action
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// actionType describes a supported action and what it needs from the target and params
+type actionType struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Target      []string `json:"target"`
+	Params      []string `json:"params,omitempty"`
+	validate    func(action model.ScheduledAction) error
+	execute     func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error)
+}
+
+var actionTypes = []actionType{
+	{
+		Name:        "member_block",
+		Description: "Заблокировать участников аккредитаций",
+		Target:      []string{"accreditation_ids", "company_ids"},
+		validate:    requireAccreditations,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setMembersBlocked(database, action, true)
+		},
+	},
+	{
+		Name:        "member_unblock",
+		Description: "Разблокировать участников аккредитаций",
+		Target:      []string{"accreditation_ids", "company_ids"},
+		validate:    requireAccreditations,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setMembersBlocked(database, action, false)
+		},
+	},
+	{
+		Name:        "gate_open",
+		Description: "Открыть зоны",
+		Target:      []string{"all", "gate_ids"},
+		validate:    requireGates,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setGatesClosed(database, action, false)
+		},
+	},
+	{
+		Name:        "gate_close",
+		Description: "Закрыть зоны",
+		Target:      []string{"all", "gate_ids"},
+		validate:    requireGates,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setGatesClosed(database, action, true)
+		},
+	},
+	{
+		Name:        "company_freeze",
+		Description: "Заморозить аккаунты компаний",
+		Target:      []string{"all", "company_ids"},
+		Params:      []string{"reason"},
+		validate:    requireCompanies,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setCompanyUsersFrozen(database, action, true)
+		},
+	},
+	{
+		Name:        "company_unfreeze",
+		Description: "Разморозить аккаунты компаний",
+		Target:      []string{"all", "company_ids"},
+		validate:    requireCompanies,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setCompanyUsersFrozen(database, action, false)
+		},
+	},
+	{
+		Name:        "company_limits_lock",
+		Description: "Запретить редакторам изменять лимиты компаний",
+		Target:      []string{"all", "company_ids"},
+		validate:    requireCompanies,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setCompanyLimitsLocked(database, action, true)
+		},
+	},
+	{
+		Name:        "company_limits_unlock",
+		Description: "Разрешить редакторам изменять лимиты компаний",
+		Target:      []string{"all", "company_ids"},
+		validate:    requireCompanies,
+		execute: func(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+			return setCompanyLimitsLocked(database, action, false)
+		},
+	},
+	{
+		Name:        "email_campaign",
+		Description: "Отправить письмо компаниям и/или на указанные адреса",
+		Target:      []string{"all", "company_ids", "emails"},
+		Params:      []string{"subject", "body"},
+		validate:    validateEmailCampaign,
+		execute:     sendEmailCampaign,
+	},
+	{
+		Name:        "report_email",
+		Description: "Сформировать отчет и отправить его на указанные адреса",
+		Target:      []string{"emails"},
+		Params:      []string{"report"},
+		validate:    validateReportEmail,
+		execute:     sendReportEmail,
+	},
+}
+
+func findActionType(name string) (actionType, bool) {
+	for _, t := range actionTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return actionType{}, false
+}
+
+func requireAccreditations(action model.ScheduledAction) error {
+	if len(action.Target.AccreditationIDs) == 0 {
+		return errors.New("не выбраны аккредитации")
+	}
+	return nil
+}
+
+func requireGates(action model.ScheduledAction) error {
+	if !action.Target.All && len(action.Target.GateIDs) == 0 {
+		return errors.New("не выбраны зоны")
+	}
+	return nil
+}
+
+func requireCompanies(action model.ScheduledAction) error {
+	if !action.Target.All && len(action.Target.CompanyIDs) == 0 {
+		return errors.New("не выбраны компании")
+	}
+	return nil
+}
+
+func validateEmailCampaign(action model.ScheduledAction) error {
+	if !action.Target.All && len(action.Target.CompanyIDs) == 0 && len(action.Target.Emails) == 0 {
+		return errors.New("не выбраны получатели")
+	}
+	if strings.TrimSpace(action.Params.Subject) == "" || strings.TrimSpace(action.Params.Body) == "" {
+		return errors.New("тема и текст письма обязательны")
+	}
+	return validateEmails(action.Target.Emails)
+}
+
+func validateReportEmail(action model.ScheduledAction) error {
+	if len(action.Target.Emails) == 0 {
+		return errors.New("не указаны адреса получателей")
+	}
+	found := false
+	for _, name := range report.ReportNames() {
+		if name == action.Params.Report {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("неизвестный отчет, доступны: %s", strings.Join(report.ReportNames(), ", "))
+	}
+	return validateEmails(action.Target.Emails)
+}
+
+func validateEmails(emails []string) error {
+	for _, email := range emails {
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("неверный адрес: %s", email)
+		}
+	}
+	return nil
+}
+
+// setMembersBlocked blocks or unblocks members of the target accreditations and logs member history
+func setMembersBlocked(database *gorm.DB, action model.ScheduledAction, blocked bool) (string, error) {
+	changeType := "unblock"
+	if blocked {
+		changeType = "block"
+	}
+	var affected int
+	err := database.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.Member{}).Where("accreditation_id IN ? AND blocked = ?", action.Target.AccreditationIDs, !blocked)
+		if len(action.Target.CompanyIDs) > 0 {
+			query = query.Where("company_id IN ?", action.Target.CompanyIDs)
+		}
+		var memberIDs []uuid.UUID
+		if err := query.Pluck("id", &memberIDs).Error; err != nil {
+			return err
+		}
+		if len(memberIDs) == 0 {
+			return nil
+		}
+		if err := tx.Model(&model.Member{}).Where("id IN ?", memberIDs).Update("blocked", blocked).Error; err != nil {
+			return err
+		}
+		histories := make([]model.MemberHistory, 0, len(memberIDs))
+		for _, memberID := range memberIDs {
+			histories = append(histories, model.MemberHistory{
+				MemberID:   memberID,
+				UserID:     action.CreatedByID,
+				ChangeType: changeType,
+				Details:    fmt.Sprintf(`{"blocked":%t,"scheduled_action_id":"%s"}`, blocked, action.ID),
+				RequestID:  requestID(action),
+			})
+		}
+		if err := tx.CreateInBatches(&histories, 200).Error; err != nil {
+			return err
+		}
+		affected = len(memberIDs)
+		return nil
+	})
+	return fmt.Sprintf("участников изменено: %d", affected), err
+}
+
+func setGatesClosed(database *gorm.DB, action model.ScheduledAction, closed bool) (string, error) {
+	query := database.Model(&model.Gate{})
+	if action.Target.All {
+		query = query.Where("1 = 1")
+	} else {
+		query = query.Where("id IN ?", action.Target.GateIDs)
+	}
+	result := query.Update("closed", closed)
+	return fmt.Sprintf("зон изменено: %d", result.RowsAffected), result.Error
+}
+
+func setCompanyUsersFrozen(database *gorm.DB, action model.ScheduledAction, frozen bool) (string, error) {
+	reason := ""
+	if frozen {
+		reason = strings.TrimSpace(action.Params.Reason)
+	}
+	var affected int
+	err := database.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.User{}).Where("role = ?", "company")
+		if !action.Target.All {
+			query = query.Where("company_id IN ?", action.Target.CompanyIDs)
+		}
+		if frozen {
+			// exempt companies keep editing
+			query = query.Where("freeze_exempt = ?", false)
+		}
+		var users []model.User
+		if err := query.Select("id", "company_id").Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		userIDs := make([]uuid.UUID, 0, len(users))
+		histories := make([]model.CompanyHistory, 0, len(users))
+		for _, user := range users {
+			userIDs = append(userIDs, user.ID)
+			histories = append(histories, model.CompanyHistory{
+				CompanyID:  user.CompanyID,
+				UserID:     action.CreatedByID,
+				ChangeType: "freeze",
+				Details:    fmt.Sprintf(`{"frozen":%t,"reason":%q,"scheduled_action_id":"%s"}`, frozen, reason, action.ID),
+				RequestID:  requestID(action),
+			})
+		}
+		if err := tx.Model(&model.User{}).Where("id IN ?", userIDs).Updates(map[string]interface{}{"frozen": frozen, "frozen_reason": reason}).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(&histories, 200).Error; err != nil {
+			return err
+		}
+		affected = len(users)
+		return nil
+	})
+	return fmt.Sprintf("аккаунтов изменено: %d", affected), err
+}
+
+func setCompanyLimitsLocked(database *gorm.DB, action model.ScheduledAction, locked bool) (string, error) {
+	query := database.Model(&model.Company{})
+	if action.Target.All {
+		query = query.Where("1 = 1")
+	} else {
+		query = query.Where("id IN ?", action.Target.CompanyIDs)
+	}
+	result := query.Update("limits_locked", locked)
+	return fmt.Sprintf("компаний изменено: %d", result.RowsAffected), result.Error
+}
+
+func sendEmailCampaign(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+	recipients := append([]string{}, action.Target.Emails...)
+	if action.Target.All || len(action.Target.CompanyIDs) > 0 {
+		query := database.Model(&model.Company{}).Where("email <> ''")
+		if !action.Target.All {
+			query = query.Where("id IN ?", action.Target.CompanyIDs)
+		}
+		var companyEmails []string
+		if err := query.Pluck("email", &companyEmails).Error; err != nil {
+			return "", err
+		}
+		recipients = append(recipients, companyEmails...)
+	}
+	return sendToRecipients(ctx, uniqueEmails(recipients), func(email string) error {
+		return smtp.SendMail(ctx, "email_campaign", email, action.Params.Subject, action.Params.Body)
+	})
+}
+
+func sendReportEmail(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (string, error) {
+	buffer := new(bytes.Buffer)
+	if err := report.Export(database, action.Params.Report, buffer); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	attachment := smtp.Attachment{
+		Name: fmt.Sprintf("%s_%s.xlsx", action.Params.Report, now.Format("20060102_1504")),
+		Data: buffer.Bytes(),
+	}
+	subject := action.Params.Subject
+	if strings.TrimSpace(subject) == "" {
+		subject = fmt.Sprintf("Отчет %s на %s", action.Params.Report, now.Format("02.01.2006 15:04"))
+	}
+	return sendToRecipients(ctx, uniqueEmails(action.Target.Emails), func(email string) error {
+		return smtp.SendMail(ctx, "report_email", email, subject, action.Params.Body, attachment)
+	})
+}
+
+// sendToRecipients sends to each address, fails only when nothing was sent
+func sendToRecipients(ctx context.Context, recipients []string, send func(email string) error) (string, error) {
+	if len(recipients) == 0 {
+		return "нет получателей", nil
+	}
+	sent := 0
+	var failed []string
+	for _, email := range recipients {
+		if ctx.Err() != nil {
+			return fmt.Sprintf("отправлено %d из %d, прервано", sent, len(recipients)), ctx.Err()
+		}
+		if err := send(email); err != nil {
+			failed = append(failed, email)
+			continue
+		}
+		sent++
+	}
+	result := fmt.Sprintf("отправлено %d из %d", sent, len(recipients))
+	if len(failed) > 0 {
+		result += "; не отправлено: " + strings.Join(failed, ", ")
+	}
+	if sent == 0 {
+		return result, errors.New("ни одно письмо не отправлено")
+	}
+	return result, nil
+}
+
+func uniqueEmails(emails []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		key := strings.ToLower(email)
+		if email == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, email)
+	}
+	return unique
+}
+
+// requestID correlates history entries written by the action
+func requestID(action model.ScheduledAction) string {
+	return "scheduled-" + action.ID.String()
+}
@@ -0,0 +1,22 @@
+package scheduledaction
+
+import (
+	"github.com/eugenetolok/evento/pkg/utils"
+	echojwt "github.com/labstack/echo-jwt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// InitScheduledActions entry point of scheduled actions
+func InitScheduledActions(g *echo.Group, dbInstance *gorm.DB, jwtConfig echojwt.Config) {
+	db = dbInstance
+	g.Use(echojwt.WithConfig(jwtConfig))
+	g.GET("", getScheduledActions, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/types", getScheduledActionTypes, utils.RoleMiddleware([]string{"admin"}))
+	g.POST("", createScheduledAction, utils.RoleMiddleware([]string{"admin"}))
+	g.GET("/:id", getScheduledAction, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.POST("/:id/cancel", cancelScheduledAction, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+	g.POST("/:id/run", runScheduledActionNow, utils.UUIDMiddleware, utils.RoleMiddleware([]string{"admin"}))
+}
@@ -0,0 +1,147 @@
+package scheduledaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/eugenetolok/evento/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func getScheduledActions(c echo.Context) error {
+	var actions []model.ScheduledAction
+	query := db.Order("execute_at DESC")
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if action := c.QueryParam("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if err := query.Find(&actions).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, actions)
+}
+
+func getScheduledActionTypes(c echo.Context) error {
+	return c.JSON(http.StatusOK, actionTypes)
+}
+
+func getScheduledAction(c echo.Context) error {
+	action, err := findScheduledAction(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusNotFound, `Действие не найдено`)
+	}
+	return c.JSON(http.StatusOK, action)
+}
+
+func createScheduledAction(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	var actionIn model.ScheduledActionIn
+	if err := c.Bind(&actionIn); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	t, ok := findActionType(strings.TrimSpace(actionIn.Action))
+	if !ok {
+		return c.String(http.StatusBadRequest, "Неизвестное действие")
+	}
+	executeAt, err := parseExecuteAt(actionIn.ExecuteAt)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	userID, _ := utils.GetUser(c)
+	action := model.ScheduledAction{
+		Action:      t.Name,
+		Target:      actionIn.Target,
+		Params:      actionIn.Params,
+		ExecuteAt:   executeAt,
+		Status:      StatusPending,
+		CreatedByID: userID,
+	}
+	action.Target.Emails = uniqueEmails(action.Target.Emails)
+	if err := t.validate(action); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err := db.Create(&action).Error; err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, action)
+}
+
+func cancelScheduledAction(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	action, err := findScheduledAction(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusNotFound, `Действие не найдено`)
+	}
+	userID, _ := utils.GetUser(c)
+	result := db.Model(&model.ScheduledAction{}).
+		Where("id = ? AND status = ?", action.ID, StatusPending).
+		Updates(map[string]interface{}{
+			"status": StatusCancelled,
+			"result": fmt.Sprintf("отменено пользователем %s", userID),
+		})
+	if result.Error != nil {
+		return c.String(http.StatusInternalServerError, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return c.String(http.StatusConflict, `Можно отменить только ожидающее действие`)
+	}
+	action, _ = findScheduledAction(action.ID.String())
+	return c.JSON(http.StatusOK, action)
+}
+
+// runScheduledActionNow executes a pending action immediately instead of waiting for its time
+func runScheduledActionNow(c echo.Context) error {
+	if !utils.CheckUserWritePermission(c, db) {
+		return c.String(http.StatusBadRequest, "Ваш аккаунт работает в режиме только для чтения")
+	}
+	action, err := findScheduledAction(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusNotFound, `Действие не найдено`)
+	}
+	if action.Status != StatusPending {
+		return c.String(http.StatusConflict, `Можно выполнить только ожидающее действие`)
+	}
+	// the action result is stored on the record, the error is reported there; the run is detached from
+	// the request so a client disconnecting midway does not abort a claimed action
+	_ = executeAction(context.WithoutCancel(c.Request().Context()), db, action)
+	action, _ = findScheduledAction(action.ID.String())
+	return c.JSON(http.StatusOK, action)
+}
+
+func findScheduledAction(rawID string) (model.ScheduledAction, error) {
+	var action model.ScheduledAction
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return action, err
+	}
+	err = db.First(&action, id).Error
+	return action, err
+}
+
+func parseExecuteAt(rawDateTime string) (time.Time, error) {
+	value := strings.TrimSpace(rawDateTime)
+	if value == "" {
+		return time.Time{}, errors.New("дата и время не указаны")
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, errors.New("неверный формат даты и времени")
+}
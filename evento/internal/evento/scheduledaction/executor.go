@@ -0,0 +1,120 @@
+package scheduledaction
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/jobs"
+	"github.com/eugenetolok/evento/pkg/model"
+	"gorm.io/gorm"
+)
+
+const schedulerInterval = 30 * time.Second
+
+// SchedulerJob executes scheduled actions that are due
+func SchedulerJob(database *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "scheduled_actions",
+		Description: "Выполняет запланированные действия",
+		Interval:    schedulerInterval,
+		RunOnStart:  true,
+		Run: func(ctx context.Context) error {
+			return runDueActions(ctx, database)
+		},
+	}
+}
+
+// FailInterrupted marks actions left running by a crash or shutdown as failed, they are not repeated
+// because a half sent email campaign must not be sent twice. Call it on startup before the scheduler runs
+func FailInterrupted(database *gorm.DB) error {
+	executedAt := time.Now()
+	result := database.Model(&model.ScheduledAction{}).Where("status = ?", StatusRunning).Updates(map[string]interface{}{
+		"status":      StatusFailed,
+		"result":      "ошибка: выполнение прервано остановкой сервера",
+		"executed_at": &executedAt,
+	})
+	if result.RowsAffected > 0 {
+		slog.Warn("interrupted scheduled actions marked as failed", "count", result.RowsAffected)
+	}
+	return result.Error
+}
+
+// runDueActions executes pending actions with execute_at in the past, oldest first
+func runDueActions(ctx context.Context, database *gorm.DB) error {
+	var due []model.ScheduledAction
+	if err := database.WithContext(ctx).
+		Where("status = ? AND execute_at <= ?", StatusPending, time.Now()).
+		Order("execute_at ASC").
+		Find(&due).Error; err != nil {
+		return err
+	}
+	failed := 0
+	for _, action := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := executeAction(ctx, database, action); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scheduled actions failed", failed, len(due))
+	}
+	return nil
+}
+
+// executeAction claims a pending action, runs it and stores status and result
+func executeAction(ctx context.Context, database *gorm.DB, action model.ScheduledAction) error {
+	claim := database.Model(&model.ScheduledAction{}).
+		Where("id = ? AND status = ?", action.ID, StatusPending).
+		Update("status", StatusRunning)
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		// cancelled or picked up by another run meanwhile
+		return nil
+	}
+
+	log := slog.With("scheduled_action_id", action.ID, "action", action.Action, "request_id", requestID(action))
+	status := StatusDone
+	result, err := runAction(ctx, database, action)
+	if err != nil {
+		status = StatusFailed
+		if result != "" {
+			result += "; "
+		}
+		result += "ошибка: " + err.Error()
+		log.Error("scheduled action failed", "error", err)
+	} else {
+		log.Info("scheduled action executed", "result", result)
+	}
+
+	executedAt := time.Now()
+	if updateErr := database.Model(&model.ScheduledAction{}).Where("id = ?", action.ID).Updates(map[string]interface{}{
+		"status":      status,
+		"result":      result,
+		"executed_at": &executedAt,
+	}).Error; updateErr != nil {
+		log.Error("unable to store scheduled action result", "error", updateErr)
+		if err == nil {
+			err = updateErr
+		}
+	}
+	return err
+}
+
+func runAction(ctx context.Context, database *gorm.DB, action model.ScheduledAction) (result string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	t, ok := findActionType(action.Action)
+	if !ok {
+		return "", fmt.Errorf("неизвестное действие %q", action.Action)
+	}
+	return t.execute(ctx, database.WithContext(ctx), action)
+}
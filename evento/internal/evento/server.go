@@ -0,0 +1,42 @@
+package evento
+
+import (
+	"net/http"
+
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// NewServer creates echo instance with middleware and all routes registered.
+// PrepareServer must be called before.
+func NewServer() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+
+	// Middleware
+	e.Use(logger.Middleware())
+	e.Use(metrics.Middleware())
+	e.Use(middleware.Recover())
+	e.Use(middleware.Gzip())
+	e.Use(middleware.Secure())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		Skipper: middleware.DefaultSkipper,
+		// checked per request so reloaded origins apply without restart
+		AllowOriginFunc: corsOriginAllowed,
+		AllowMethods:    []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+		ExposeHeaders:   []string{"Content-Disposition", "Content-Type", "Content-Length"},
+	}))
+
+	// Static SPA serve
+	// e.Use(middleware.StaticWithConfig(middleware.StaticConfig{
+	// 	Root:   "dist",       // This is the path to your SPA build folder, the folder that is created from running "npm build"
+	// 	Index:  "index.html", // This is the default html page for your SPA
+	// 	Browse: false,
+	// 	HTML5:  true,
+	// }))
+
+	Routes(e)
+	return e
+}
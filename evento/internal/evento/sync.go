@@ -2,10 +2,12 @@ package evento
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
+	"gorm.io/gorm"
 )
 
 func syncDerivedCompanyFieldsOnce() {
@@ -35,7 +37,7 @@ func syncDerivedCompanyFieldsOnce() {
 
 	for _, query := range queries {
 		if err := db.Exec(query.sql).Error; err != nil {
-			log.Printf("startup sync failed (%s): %v", query.description, err)
+			slog.Error("startup sync failed", "query", query.description, "error", err)
 		}
 	}
 }
@@ -43,18 +45,24 @@ func syncDerivedCompanyFieldsOnce() {
 func syncEmptyMemberBarcodesOnce() {
 	var members []model.Member
 	if err := db.Where("barcode = '' OR barcode IS NULL").Find(&members).Error; err != nil {
-		log.Printf("startup barcode sync failed while selecting members: %v", err)
+		slog.Error("startup barcode sync failed while selecting members", "error", err)
 		return
 	}
 
 	for _, member := range members {
 		barcode, err := generateUniqueMemberBarcodeForSync()
 		if err != nil {
-			log.Printf("startup barcode generation failed for member %s: %v", member.ID.String(), err)
+			slog.Error("startup barcode generation failed", "member_id", member.ID, "error", err)
 			continue
 		}
-		if err := db.Model(&model.Member{}).Where("id = ?", member.ID).Update("barcode", barcode).Error; err != nil {
-			log.Printf("startup barcode sync failed for member %s: %v", member.ID.String(), err)
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.Member{}).Where("id = ?", member.ID).Update("barcode", barcode).Error; err != nil {
+				return err
+			}
+			return tx.Create(&model.MemberBarcode{MemberID: member.ID, Barcode: barcode, IssuedAt: time.Now()}).Error
+		})
+		if err != nil {
+			slog.Error("startup barcode sync failed", "member_id", member.ID, "error", err)
 		}
 	}
 }
@@ -71,11 +79,14 @@ func generateUniqueMemberBarcodeForSync() (string, error) {
 			continue
 		}
 
-		var count int64
+		var count, revoked int64
 		if err := db.Model(&model.Member{}).Where("barcode = ?", candidate).Count(&count).Error; err != nil {
 			return "", err
 		}
-		if count == 0 {
+		if err := db.Model(&model.MemberBarcode{}).Where("barcode = ?", candidate).Count(&revoked).Error; err != nil {
+			return "", err
+		}
+		if count == 0 && revoked == 0 {
 			return candidate, nil
 		}
 	}
@@ -86,22 +97,207 @@ func generateUniqueMemberBarcodeForSync() (string, error) {
 func ensureUserFreezeScheduleColumns() {
 	if !db.Migrator().HasColumn(&model.User{}, "FrozenAt") {
 		if err := db.Migrator().AddColumn(&model.User{}, "FrozenAt"); err != nil {
-			log.Printf("unable to add users.frozen_at column: %v", err)
+			slog.Error("unable to add users.frozen_at column", "error", err)
 		}
 	}
 	if !db.Migrator().HasColumn(&model.User{}, "FrozenAction") {
 		if err := db.Migrator().AddColumn(&model.User{}, "FrozenAction"); err != nil {
-			log.Printf("unable to add users.frozen_action column: %v", err)
+			slog.Error("unable to add users.frozen_action column", "error", err)
+		}
+	}
+	if !db.Migrator().HasColumn(&model.User{}, "FrozenReason") {
+		if err := db.Migrator().AddColumn(&model.User{}, "FrozenReason"); err != nil {
+			slog.Error("unable to add users.frozen_reason column", "error", err)
+		}
+	}
+	if !db.Migrator().HasColumn(&model.User{}, "FreezeExempt") {
+		if err := db.Migrator().AddColumn(&model.User{}, "FreezeExempt"); err != nil {
+			slog.Error("unable to add users.freeze_exempt column", "error", err)
 		}
 	}
 	if !db.Migrator().HasColumn(&model.User{}, "PasswordResetTokenHash") {
 		if err := db.Migrator().AddColumn(&model.User{}, "PasswordResetTokenHash"); err != nil {
-			log.Printf("unable to add users.password_reset_token_hash column: %v", err)
+			slog.Error("unable to add users.password_reset_token_hash column", "error", err)
 		}
 	}
 	if !db.Migrator().HasColumn(&model.User{}, "PasswordResetExpiresAt") {
 		if err := db.Migrator().AddColumn(&model.User{}, "PasswordResetExpiresAt"); err != nil {
-			log.Printf("unable to add users.password_reset_expires_at column: %v", err)
+			slog.Error("unable to add users.password_reset_expires_at column", "error", err)
+		}
+	}
+}
+
+// ensureHistoryRequestIDColumns adds request_id to history tables of databases created before it existed
+func ensureHistoryRequestIDColumns() {
+	for _, history := range []interface{}{&model.MemberHistory{}, &model.CompanyHistory{}, &model.AutoHistory{}} {
+		if db.Migrator().HasColumn(history, "RequestID") {
+			continue
+		}
+		if err := db.Migrator().AddColumn(history, "RequestID"); err != nil {
+			slog.Error("unable to add history request_id column", "error", err)
+			continue
+		}
+		if err := db.Migrator().CreateIndex(history, "RequestID"); err != nil {
+			slog.Error("unable to index history request_id column", "error", err)
+		}
+	}
+}
+
+// ensureScheduledActionSchema creates the scheduled actions table and columns the actions toggle
+func ensureScheduledActionSchema() {
+	if err := db.AutoMigrate(&model.ScheduledAction{}); err != nil {
+		slog.Error("unable to migrate scheduled_actions table", "error", err)
+	}
+	if !db.Migrator().HasColumn(&model.Gate{}, "Closed") {
+		if err := db.Migrator().AddColumn(&model.Gate{}, "Closed"); err != nil {
+			slog.Error("unable to add gates.closed column", "error", err)
+		}
+	}
+	if !db.Migrator().HasColumn(&model.Company{}, "LimitsLocked") {
+		if err := db.Migrator().AddColumn(&model.Company{}, "LimitsLocked"); err != nil {
+			slog.Error("unable to add companies.limits_locked column", "error", err)
+		}
+	}
+}
+
+// ensureRegistrationDeadlineSchema adds registration deadline columns and the company overrides table
+func ensureRegistrationDeadlineSchema() {
+	if err := db.AutoMigrate(&model.CompanyDeadlineOverride{}); err != nil {
+		slog.Error("unable to migrate company_deadline_overrides table", "error", err)
+	}
+	if !db.Migrator().HasColumn(&model.Accreditation{}, "RegistrationDeadline") {
+		if err := db.Migrator().AddColumn(&model.Accreditation{}, "RegistrationDeadline"); err != nil {
+			slog.Error("unable to add accreditations.registration_deadline column", "error", err)
+		}
+	}
+	if !db.Migrator().HasColumn(&model.Event{}, "RegistrationDeadline") {
+		if err := db.Migrator().AddColumn(&model.Event{}, "RegistrationDeadline"); err != nil {
+			slog.Error("unable to add events.registration_deadline column", "error", err)
+		}
+	}
+}
+
+// ensureLimitChangeRequestSchema creates the limit change request tables and allows one pending request per company
+func ensureLimitChangeRequestSchema() {
+	if err := db.AutoMigrate(&model.LimitChangeRequest{}, &model.LimitChangeRequestItem{}); err != nil {
+		slog.Error("unable to migrate limit change request tables", "error", err)
+		return
+	}
+	// one pending request per company, parallel submits must not both pass the pending check
+	err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_limit_change_requests_pending ON limit_change_requests (company_id) WHERE status = 'pending' AND deleted_at IS NULL").Error
+	if err != nil {
+		slog.Error("unable to add one pending limit request index", "error", err)
+	}
+}
+
+// ensureCapacitySchema adds capacity columns to accreditations, events and gates
+func ensureCapacitySchema() {
+	for _, value := range []interface{}{&model.Accreditation{}, &model.Event{}, &model.Gate{}} {
+		if db.Migrator().HasColumn(value, "Capacity") {
+			continue
 		}
+		if err := db.Migrator().AddColumn(value, "Capacity"); err != nil {
+			slog.Error("unable to add capacity column", "model", fmt.Sprintf("%T", value), "error", err)
+		}
+	}
+}
+
+// ensurePrintJobSchema creates print jobs and print override approvals and adds print details to member prints,
+// member_prints is altered column by column because the ai_member_prints view blocks rebuilding the table
+func ensurePrintJobSchema() {
+	if err := db.AutoMigrate(&model.PrintJob{}, &model.PrintOverrideApproval{}); err != nil {
+		slog.Error("unable to migrate print jobs", "error", err)
+	}
+	if !db.Migrator().HasTable(&model.MemberPrint{}) {
+		if err := db.AutoMigrate(&model.MemberPrint{}); err != nil {
+			slog.Error("unable to migrate member prints", "error", err)
+		}
+		return
+	}
+	for _, field := range []string{"PrintJobID", "OperatorID", "Device", "Printer", "TemplateID", "Reprint", "Reason", "InvalidatedBarcode", "Overridden"} {
+		if db.Migrator().HasColumn(&model.MemberPrint{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&model.MemberPrint{}, field); err != nil {
+			slog.Error("unable to add member prints column", "field", field, "error", err)
+		}
+	}
+	if !db.Migrator().HasIndex(&model.MemberPrint{}, "PrintJobID") {
+		if err := db.Migrator().CreateIndex(&model.MemberPrint{}, "PrintJobID"); err != nil {
+			slog.Error("unable to add member_prints.print_job_id index", "error", err)
+		}
+	}
+}
+
+// ensureCredentialKeySchema creates the table of credential signing keys
+func ensureCredentialKeySchema() {
+	if err := db.AutoMigrate(&model.CredentialKey{}); err != nil {
+		slog.Error("unable to migrate credential keys", "error", err)
+	}
+}
+
+// ensureBadgeTemplateSchema adds template versions, company templates and accreditation templates,
+// existing templates get their current content as version 1
+func ensureBadgeTemplateSchema() {
+	if err := db.AutoMigrate(&model.BadgeTemplate{}, &model.BadgeTemplateVersion{}, &model.CompanyBadgeTemplate{}); err != nil {
+		slog.Error("unable to migrate badge template tables", "error", err)
+		return
+	}
+	if !db.Migrator().HasColumn(&model.Accreditation{}, "BadgeTemplateID") {
+		if err := db.Migrator().AddColumn(&model.Accreditation{}, "BadgeTemplateID"); err != nil {
+			slog.Error("unable to add accreditations.badge_template_id column", "error", err)
+		}
+	}
+	var templates []model.BadgeTemplate
+	if err := db.Where("version = 0 OR version IS NULL").Find(&templates).Error; err != nil {
+		slog.Error("unable to load badge templates", "error", err)
+		return
+	}
+	for _, template := range templates {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.BadgeTemplate{}).Where("id = ?", template.ID).Update("version", 1).Error; err != nil {
+				return err
+			}
+			return tx.Create(&model.BadgeTemplateVersion{TemplateID: template.ID, Version: 1, Name: template.Name, TemplateJSON: template.TemplateJSON}).Error
+		})
+		if err != nil {
+			slog.Error("unable to version badge template", "template_id", template.ID, "error", err)
+		}
+	}
+}
+
+// ensureMemberBarcodeSchema creates the barcode history, current barcodes of existing members
+// are recorded as issued when the member was created
+func ensureMemberBarcodeSchema() {
+	if err := db.AutoMigrate(&model.MemberBarcode{}); err != nil {
+		slog.Error("unable to migrate member barcodes", "error", err)
+		return
+	}
+	var members []model.Member
+	err := db.Select("id", "barcode", "created_at").
+		Where("barcode <> '' AND barcode IS NOT NULL AND id NOT IN (SELECT member_id FROM member_barcodes)").
+		FindInBatches(&members, 500, func(tx *gorm.DB, batch int) error {
+			history := make([]model.MemberBarcode, 0, len(members))
+			for _, member := range members {
+				history = append(history, model.MemberBarcode{MemberID: member.ID, Barcode: member.Barcode, IssuedAt: member.CreatedAt})
+			}
+			return db.Create(&history).Error
+		}).Error
+	if err != nil {
+		slog.Error("unable to record member barcodes", "error", err)
+	}
+}
+
+// ensureBangleSchema creates bangle types, issuing points, stock and issues
+func ensureBangleSchema() {
+	if err := db.AutoMigrate(&model.BangleType{}, &model.BanglePoint{}, &model.BangleStock{}, &model.BangleIssue{}); err != nil {
+		slog.Error("unable to migrate bangle tables", "error", err)
+	}
+}
+
+// ensureMemberCredentialSchema creates member credentials
+func ensureMemberCredentialSchema() {
+	if err := db.AutoMigrate(&model.MemberCredential{}); err != nil {
+		slog.Error("unable to migrate member credentials", "error", err)
 	}
 }
@@ -2,9 +2,9 @@ package user
 
 import (
 	"errors"
-	"log"
 	"net/http"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/google/uuid"
 	"github.com/jinzhu/copier"
@@ -51,7 +51,7 @@ func createUser(c echo.Context) error {
 	copier.Copy(&user, &userIn)
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Println("password hashing failed", err)
+		logger.FromEcho(c).Error("password hashing failed", "error", err)
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	user.Password = string(hashedPassword)
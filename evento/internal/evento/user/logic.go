@@ -3,6 +3,7 @@ package user
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/utils"
@@ -61,8 +62,20 @@ func me(c echo.Context) error {
 	return c.JSON(http.StatusOK, user)
 }
 
+// FrozenStatus is the freeze state shown to the user, ScheduledAction/ScheduledAt is the upcoming deadline
+type FrozenStatus struct {
+	Frozen          bool       `json:"frozen"`
+	Reason          string     `json:"reason,omitempty"`
+	ScheduledAction string     `json:"scheduled_action,omitempty"`
+	ScheduledAt     *time.Time `json:"scheduled_at,omitempty"`
+	Exempt          bool       `json:"exempt"`
+}
+
+// frozen returns whether the user is frozen, with ?details=1 also reason and scheduled deadline
 func frozen(c echo.Context) error {
 	userID, _ := utils.GetUser(c)
+	// applies a due scheduled freeze before reporting
+	utils.CheckUserWritePermission(c, db)
 	var user model.User
 	if err := db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -70,5 +83,14 @@ func frozen(c echo.Context) error {
 		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
-	return c.JSON(http.StatusOK, user.Frozen)
+	if c.QueryParam("details") == "" {
+		return c.JSON(http.StatusOK, user.Frozen)
+	}
+	return c.JSON(http.StatusOK, FrozenStatus{
+		Frozen:          user.Frozen,
+		Reason:          user.FrozenReason,
+		ScheduledAction: user.FrozenAction,
+		ScheduledAt:     user.FrozenAt,
+		Exempt:          user.FreezeExempt,
+	})
 }
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Roles lists the roles a user can have
+var Roles = []string{"admin", "editor", "company", "operator", "monitoring"}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateUser hashes the password and stores a new user
+func CreateUser(database *gorm.DB, username, password, role string) (model.User, error) {
+	var user model.User
+	username = strings.TrimSpace(username)
+	role = strings.TrimSpace(role)
+	if username == "" {
+		return user, errors.New("username is required")
+	}
+	if !ValidRole(role) {
+		return user, fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(Roles, ", "))
+	}
+	if len(password) < minPasswordLength {
+		return user, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	var count int64
+	if err := database.Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return user, err
+	}
+	if count > 0 {
+		return user, fmt.Errorf("user %q already exists", username)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, fmt.Errorf("password hashing failed: %w", err)
+	}
+	user.Username = username
+	user.Role = role
+	user.Password = string(hashedPassword)
+	if err := database.Create(&user).Error; err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// ListUsers returns users ordered by username, optionally filtered by role
+func ListUsers(database *gorm.DB, role string) ([]model.User, error) {
+	var users []model.User
+	query := database.Order("username asc")
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindUser looks a user up by id or username
+func FindUser(database *gorm.DB, ref string) (model.User, error) {
+	var user model.User
+	query := database.Where("username = ?", ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		query = database.Where("id = ?", id)
+	}
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, fmt.Errorf("user %q is not found", ref)
+		}
+		return user, err
+	}
+	return user, nil
+}
+
+// SetUserPassword replaces the password and invalidates pending reset tokens
+func SetUserPassword(database *gorm.DB, user model.User, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("password hashing failed: %w", err)
+	}
+	return database.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"password":                  string(hashedPassword),
+		"password_reset_token_hash": "",
+		"password_reset_expires_at": nil,
+	}).Error
+}
+
+// SetUserFrozen freezes or unfreezes the user immediately
+func SetUserFrozen(database *gorm.DB, user model.User, frozen bool) error {
+	return database.Model(&model.User{}).Where("id = ?", user.ID).Update("frozen", frozen).Error
+}
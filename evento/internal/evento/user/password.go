@@ -2,11 +2,11 @@ package user
 
 import (
 	"errors"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/eugenetolok/evento/pkg/smtp"
 	"github.com/eugenetolok/evento/pkg/utils"
@@ -80,7 +80,7 @@ func resetPassword(c echo.Context) error {
 	if newPassword != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 		if err != nil {
-			log.Println("password hashing failed", err)
+			logger.FromEcho(c).Error("password hashing failed", "error", err)
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
 		updates["password"] = string(hashedPassword)
@@ -105,7 +105,7 @@ func resetPassword(c echo.Context) error {
 	}
 
 	if recipientEmail != "" {
-		if ok := smtp.SendPasswordResetLink(recipientEmail, user, tokenForEmail, expiresAt); !ok {
+		if ok := smtp.SendPasswordResetLink(c.Request().Context(), recipientEmail, user, tokenForEmail, expiresAt); !ok {
 			_ = db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
 				"password_reset_token_hash": "",
 				"password_reset_expires_at": nil,
@@ -159,7 +159,7 @@ func CompleteResetPassword(c echo.Context) error {
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
-		log.Println("password hashing failed", err)
+		logger.FromEcho(c).Error("password hashing failed", "error", err)
 		return c.String(http.StatusInternalServerError, `{"error":"internal server error"}`)
 	}
 
@@ -0,0 +1,154 @@
+// Package credential encodes signed member credentials which scanners verify offline with Ed25519 public keys.
+//
+// A credential is "EV1:" followed by base64url (no padding) of
+//
+//	version(1) | key id length(1) | key id | member id(16) | accreditation id(16) | barcode fingerprint(8) |
+//	not before(4, unix) | expires at(4, unix) | gate count(1) | gate ids(16 each) | signature(64)
+//
+// the signature covers every byte before it. The fingerprint binds the credential to the member barcode,
+// a credential of a replaced barcode does not match the current one.
+package credential
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prefix starts every credential, plain barcodes never contain it
+const Prefix = "EV1:"
+
+const version = 2
+
+// fingerprintSize is the length of the barcode fingerprint in bytes
+const fingerprintSize = 8
+
+// Verification errors
+var (
+	ErrMalformed   = errors.New("credential is malformed")
+	ErrUnknownKey  = errors.New("credential key is unknown")
+	ErrSignature   = errors.New("credential signature is invalid")
+	ErrNotYetValid = errors.New("credential is not valid yet")
+	ErrExpired     = errors.New("credential is expired")
+)
+
+// Claims are the signed contents of a credential
+type Claims struct {
+	KeyID           string    `json:"key_id"`
+	MemberID        uuid.UUID `json:"member_id"`
+	AccreditationID uuid.UUID `json:"accreditation_id"`
+	// Fingerprint is the hex Fingerprint of the member barcode at issue time
+	Fingerprint string      `json:"fingerprint"`
+	Gates       []uuid.UUID `json:"gates"`
+	NotBefore   time.Time   `json:"not_before"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
+
+// IsCredential reports whether the scanned code is a credential and not a plain barcode
+func IsCredential(code string) bool {
+	return strings.HasPrefix(code, Prefix)
+}
+
+// Fingerprint returns the first bytes of SHA-256 of the barcode in hex, scanners compare it
+// with the fingerprint of the current member barcode
+func Fingerprint(barcode string) string {
+	hash := sha256.Sum256([]byte(barcode))
+	return hex.EncodeToString(hash[:fingerprintSize])
+}
+
+// Sign encodes the claims and signs them with the private key
+func Sign(claims Claims, key ed25519.PrivateKey) (string, error) {
+	fingerprint, err := hex.DecodeString(claims.Fingerprint)
+	if err != nil || len(fingerprint) != fingerprintSize || len(claims.KeyID) == 0 || len(claims.KeyID) > 255 || len(claims.Gates) > 255 {
+		return "", ErrMalformed
+	}
+	data := make([]byte, 0, 2+len(claims.KeyID)+41+fingerprintSize+16*len(claims.Gates)+ed25519.SignatureSize)
+	data = append(data, version, byte(len(claims.KeyID)))
+	data = append(data, claims.KeyID...)
+	data = append(data, claims.MemberID[:]...)
+	data = append(data, claims.AccreditationID[:]...)
+	data = append(data, fingerprint...)
+	data = binary.BigEndian.AppendUint32(data, uint32(claims.NotBefore.Unix()))
+	data = binary.BigEndian.AppendUint32(data, uint32(claims.ExpiresAt.Unix()))
+	data = append(data, byte(len(claims.Gates)))
+	for _, gate := range claims.Gates {
+		data = append(data, gate[:]...)
+	}
+	data = append(data, ed25519.Sign(key, data)...)
+	return Prefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Parse decodes the claims without checking the signature
+func Parse(code string) (Claims, error) {
+	claims, _, _, err := parse(code)
+	return claims, err
+}
+
+// Verify checks the signature with the key returned by keys and the validity window at now
+func Verify(code string, keys func(keyID string) (ed25519.PublicKey, bool), now time.Time) (Claims, error) {
+	claims, signed, signature, err := parse(code)
+	if err != nil {
+		return claims, err
+	}
+	key, ok := keys(claims.KeyID)
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return claims, ErrUnknownKey
+	}
+	if !ed25519.Verify(key, signed, signature) {
+		return claims, ErrSignature
+	}
+	if now.Before(claims.NotBefore) {
+		return claims, ErrNotYetValid
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+func parse(code string) (Claims, []byte, []byte, error) {
+	var claims Claims
+	if !IsCredential(code) {
+		return claims, nil, nil, ErrMalformed
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(code, Prefix))
+	if err != nil || len(data) < 2 || data[0] != version {
+		return claims, nil, nil, ErrMalformed
+	}
+	keyLength := int(data[1])
+	fixed := 2 + keyLength + 16 + 16 + fingerprintSize + 4 + 4 + 1
+	if len(data) < fixed+ed25519.SignatureSize {
+		return claims, nil, nil, ErrMalformed
+	}
+	gateCount := int(data[fixed-1])
+	signedLength := fixed + 16*gateCount
+	if len(data) != signedLength+ed25519.SignatureSize {
+		return claims, nil, nil, ErrMalformed
+	}
+	offset := 2
+	claims.KeyID = string(data[offset : offset+keyLength])
+	offset += keyLength
+	copy(claims.MemberID[:], data[offset:offset+16])
+	offset += 16
+	copy(claims.AccreditationID[:], data[offset:offset+16])
+	offset += 16
+	claims.Fingerprint = hex.EncodeToString(data[offset : offset+fingerprintSize])
+	offset += fingerprintSize
+	claims.NotBefore = time.Unix(int64(binary.BigEndian.Uint32(data[offset:])), 0).UTC()
+	offset += 4
+	claims.ExpiresAt = time.Unix(int64(binary.BigEndian.Uint32(data[offset:])), 0).UTC()
+	offset += 5
+	claims.Gates = make([]uuid.UUID, gateCount)
+	for i := range claims.Gates {
+		copy(claims.Gates[i][:], data[offset:offset+16])
+		offset += 16
+	}
+	return claims, data[:signedLength], data[signedLength:], nil
+}
@@ -0,0 +1,57 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	disimaging "github.com/disintegration/imaging"
+)
+
+// FaceDetector reports whether a photo shows a face, implementations may wrap a local model or a remote service
+type FaceDetector interface {
+	HasFace(img image.Image) (bool, error)
+}
+
+// NewFaceDetector returns the detector by name: skin or none, none disables the check and returns nil
+func NewFaceDetector(name string) (FaceDetector, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "skin":
+		return SkinDetector{MinRatio: 0.08}, nil
+	}
+	return nil, fmt.Errorf("unknown face detector %q", name)
+}
+
+// SkinDetector is a heuristic, not a face detector: a portrait has enough skin colored pixels in the middle of the frame.
+// It rejects empty, document and landscape shots but does not tell a face from other skin
+type SkinDetector struct {
+	// MinRatio is the share of skin pixels in the central area
+	MinRatio float64
+}
+
+// HasFace implements FaceDetector
+func (d SkinDetector) HasFace(img image.Image) (bool, error) {
+	small := disimaging.Resize(img, 64, 0, disimaging.Box)
+	bounds := small.Bounds()
+	var skin, total int
+	for y := bounds.Min.Y + bounds.Dy()*15/100; y < bounds.Min.Y+bounds.Dy()*75/100; y++ {
+		for x := bounds.Min.X + bounds.Dx()/4; x < bounds.Min.X+bounds.Dx()*3/4; x++ {
+			total++
+			if isSkin(small.NRGBAAt(x, y)) {
+				skin++
+			}
+		}
+	}
+	if total == 0 {
+		return false, nil
+	}
+	return float64(skin)/float64(total) >= d.MinRatio, nil
+}
+
+// isSkin uses the common YCbCr skin range which holds for most skin tones
+func isSkin(pixel color.NRGBA) bool {
+	y, cb, cr := color.RGBToYCbCr(pixel.R, pixel.G, pixel.B)
+	return y > 40 && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
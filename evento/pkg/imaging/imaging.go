@@ -0,0 +1,118 @@
+// Package imaging normalizes member photos: orientation, cropping, compression and thumbnails.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+
+	disimaging "github.com/disintegration/imaging"
+)
+
+// MaxPixels caps the size of images which are decoded, a small file may declare a huge canvas
+const MaxPixels = 40_000_000
+
+// Errors of decoding
+var (
+	ErrDecode   = errors.New("image can not be decoded")
+	ErrTooLarge = errors.New("image has too many pixels")
+)
+
+// Options of photo normalization, zero values keep the photo as it is
+type Options struct {
+	// AspectWidth:AspectHeight is the aspect ratio of the badge photo
+	AspectWidth   int
+	AspectHeight  int
+	MaxWidth      int
+	Quality       int
+	ThumbnailSize int
+}
+
+// Result is the normalized photo and its thumbnail encoded as jpeg
+type Result struct {
+	Image     image.Image
+	Photo     []byte
+	Thumbnail []byte
+}
+
+// Normalize rotates the photo by its EXIF orientation, crops it to the aspect ratio around the center,
+// scales it down to MaxWidth and encodes it as jpeg, the encoded file carries no EXIF or GPS data
+func Normalize(r io.Reader, options Options) (Result, error) {
+	img, err := Decode(r)
+	if err != nil {
+		return Result{}, err
+	}
+	img = CropToAspect(img, options.AspectWidth, options.AspectHeight)
+	if options.MaxWidth > 0 && img.Bounds().Dx() > options.MaxWidth {
+		img = disimaging.Resize(img, options.MaxWidth, 0, disimaging.Lanczos)
+	}
+	// transparent png pixels would turn black in jpeg
+	bounds := img.Bounds()
+	img = disimaging.Overlay(disimaging.New(bounds.Dx(), bounds.Dy(), color.White), img, image.Pt(0, 0), 1)
+	result := Result{Image: img}
+	if result.Photo, err = EncodeJPEG(img, options.Quality); err != nil {
+		return result, err
+	}
+	if options.ThumbnailSize > 0 {
+		if result.Thumbnail, err = EncodeJPEG(Thumbnail(img, options.ThumbnailSize), options.Quality); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// CropToAspect cuts the largest centered area with the aspect ratio width:height
+func CropToAspect(img image.Image, width, height int) image.Image {
+	if width <= 0 || height <= 0 {
+		return img
+	}
+	bounds := img.Bounds()
+	cropWidth, cropHeight := bounds.Dx(), bounds.Dx()*height/width
+	if cropHeight > bounds.Dy() {
+		cropWidth, cropHeight = bounds.Dy()*width/height, bounds.Dy()
+	}
+	if cropWidth == bounds.Dx() && cropHeight == bounds.Dy() {
+		return img
+	}
+	return disimaging.CropCenter(img, cropWidth, cropHeight)
+}
+
+// Thumbnail fits the image into size x size, smaller images are not enlarged
+func Thumbnail(img image.Image, size int) image.Image {
+	return disimaging.Fit(img, size, size, disimaging.Lanczos)
+}
+
+// Decode reads a jpeg or png image applying its EXIF orientation, the header is checked first
+// and images over MaxPixels are rejected with ErrTooLarge before their pixels are allocated
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrDecode
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, err := disimaging.Decode(bytes.NewReader(data), disimaging.AutoOrientation(true))
+	if err != nil {
+		return nil, ErrDecode
+	}
+	return img, nil
+}
+
+// EncodeJPEG encodes the image with the quality from 1 to 100, 85 when it is out of range
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	if quality < 1 || quality > 100 {
+		quality = 85
+	}
+	var buffer bytes.Buffer
+	if err := disimaging.Encode(&buffer, img, disimaging.JPEG, disimaging.JPEGQuality(quality)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,297 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eugenetolok/evento/pkg/metrics"
+)
+
+// historySize is how many finished runs are kept per job
+const historySize = 20
+
+// Trigger kinds of a run
+const (
+	TriggerStartup  = "startup"
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	// ErrNotFound is returned for unknown job names
+	ErrNotFound = errors.New("job is not found")
+	// ErrRunning is returned when a job is triggered while it is still running
+	ErrRunning = errors.New("job is already running")
+	// ErrStopped is returned when the runner is not accepting runs
+	ErrStopped = errors.New("job runner is stopped")
+)
+
+// Job is a background task run on an interval
+type Job struct {
+	Name        string
+	Description string
+	// Interval between scheduled runs, zero means manual trigger only
+	Interval time.Duration
+	// RunOnStart runs the job once right after the runner starts
+	RunOnStart bool
+	Run        func(ctx context.Context) error
+}
+
+// Run is a finished job run
+type Run struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is a snapshot of job state
+type Status struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Interval    string     `json:"interval"`
+	Running     bool       `json:"running"`
+	Runs        int        `json:"runs"`
+	Failures    int        `json:"failures"`
+	LastRun     *Run       `json:"last_run,omitempty"`
+	LastError   *Run       `json:"last_error,omitempty"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	History     []Run      `json:"history"`
+}
+
+type jobState struct {
+	job       Job
+	running   bool
+	runs      int
+	failures  int
+	lastError *Run
+	nextRunAt time.Time
+	history   []Run
+}
+
+// Runner schedules registered jobs and tracks their status
+type Runner struct {
+	mu      sync.Mutex
+	jobs    map[string]*jobState
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewRunner creates an empty runner
+func NewRunner() *Runner {
+	return &Runner{jobs: map[string]*jobState{}}
+}
+
+// Register adds a job, must be called before Start
+func (r *Runner) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("job name and run func are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return fmt.Errorf("job %q registered after runner start", job.Name)
+	}
+	if _, ok := r.jobs[job.Name]; ok {
+		return fmt.Errorf("job %q is already registered", job.Name)
+	}
+	r.jobs[job.Name] = &jobState{job: job}
+	return nil
+}
+
+// Start launches schedules of all jobs, runs stop when ctx is done or Stop is called
+func (r *Runner) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return
+	}
+	r.started = true
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	for _, state := range r.jobs {
+		state := state
+		if state.job.RunOnStart {
+			r.launchLocked(state, TriggerStartup)
+		}
+		if state.job.Interval > 0 {
+			state.nextRunAt = time.Now().Add(state.job.Interval)
+			r.wg.Add(1)
+			go r.schedule(state)
+		}
+	}
+}
+
+// Stop cancels running jobs and waits for them to return or ctx to expire
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopped = true
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs did not stop in time: %w", ctx.Err())
+	}
+}
+
+// Trigger starts a job run now without waiting for it to finish
+func (r *Runner) Trigger(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.jobs[name]
+	if !ok {
+		return ErrNotFound
+	}
+	if !r.started || r.stopped {
+		return ErrStopped
+	}
+	if state.running {
+		return ErrRunning
+	}
+	r.launchLocked(state, TriggerManual)
+	return nil
+}
+
+// Statuses returns a snapshot of all jobs ordered by name
+func (r *Runner) Statuses() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]Status, 0, len(r.jobs))
+	for _, state := range r.jobs {
+		statuses = append(statuses, state.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Status returns a snapshot of one job
+func (r *Runner) Status(name string) (Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.jobs[name]
+	if !ok {
+		return Status{}, ErrNotFound
+	}
+	return state.status(), nil
+}
+
+func (r *Runner) schedule(state *jobState) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(state.job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			state.nextRunAt = time.Now().Add(state.job.Interval)
+			if state.running {
+				slog.Warn("job is still running, scheduled run skipped", "job", state.job.Name)
+			} else if !r.stopped {
+				r.launchLocked(state, TriggerSchedule)
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// launchLocked starts a run in its own goroutine, r.mu must be held
+func (r *Runner) launchLocked(state *jobState, trigger string) {
+	state.running = true
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.execute(state, trigger)
+	}()
+}
+
+func (r *Runner) execute(state *jobState, trigger string) {
+	run := Run{Trigger: trigger, StartedAt: time.Now()}
+	err := safeRun(r.ctx, state.job.Run)
+	run.FinishedAt = time.Now()
+	run.DurationMS = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+	}
+	metrics.ObserveJobRun(state.job.Name, run.StartedAt, err)
+
+	r.mu.Lock()
+	state.running = false
+	state.runs++
+	if err != nil {
+		state.failures++
+		state.lastError = &run
+	}
+	state.history = append(state.history, run)
+	if len(state.history) > historySize {
+		state.history = state.history[len(state.history)-historySize:]
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		slog.Error("job failed", "job", state.job.Name, "trigger", trigger, "duration_ms", run.DurationMS, "error", err)
+		return
+	}
+	slog.Debug("job finished", "job", state.job.Name, "trigger", trigger, "duration_ms", run.DurationMS)
+}
+
+// safeRun converts a panic inside the job into an error
+func safeRun(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return run(ctx)
+}
+
+func (state *jobState) status() Status {
+	status := Status{
+		Name:        state.job.Name,
+		Description: state.job.Description,
+		Interval:    state.job.Interval.String(),
+		Running:     state.running,
+		Runs:        state.runs,
+		Failures:    state.failures,
+		History:     make([]Run, 0, len(state.history)),
+	}
+	if state.job.Interval == 0 {
+		status.Interval = "manual"
+	}
+	if state.lastError != nil {
+		lastError := *state.lastError
+		status.LastError = &lastError
+	}
+	if len(state.history) > 0 {
+		lastRun := state.history[len(state.history)-1]
+		status.LastRun = &lastRun
+	}
+	if !state.nextRunAt.IsZero() {
+		nextRunAt := state.nextRunAt
+		status.NextRunAt = &nextRunAt
+	}
+	// newest first
+	for i := len(state.history) - 1; i >= 0; i-- {
+		status.History = append(status.History, state.history[i])
+	}
+	return status
+}
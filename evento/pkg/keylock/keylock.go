@@ -0,0 +1,65 @@
+// Package keylock provides mutexes keyed by string, used to serialize work per company
+package keylock
+
+import (
+	"sort"
+	"sync"
+)
+
+// Locker holds one mutex per key, unused keys are released
+type Locker struct {
+	mu    sync.Mutex
+	locks map[string]*entry
+}
+
+type entry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// New returns an empty Locker
+func New() *Locker {
+	return &Locker{locks: map[string]*entry{}}
+}
+
+// Lock blocks until all keys are held and returns the function releasing them,
+// keys are taken in sorted order so callers locking several keys do not deadlock
+func (l *Locker) Lock(keys ...string) (unlock func()) {
+	keys = uniqueSorted(keys)
+	entries := make([]*entry, 0, len(keys))
+	for _, key := range keys {
+		l.mu.Lock()
+		e, ok := l.locks[key]
+		if !ok {
+			e = &entry{}
+			l.locks[key] = e
+		}
+		e.refs++
+		l.mu.Unlock()
+		e.mu.Lock()
+		entries = append(entries, e)
+	}
+	return func() {
+		for i := len(entries) - 1; i >= 0; i-- {
+			entries[i].mu.Unlock()
+			l.mu.Lock()
+			entries[i].refs--
+			if entries[i].refs == 0 {
+				delete(l.locks, keys[i])
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+func uniqueSorted(keys []string) []string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, key := range sorted {
+		if i == 0 || key != sorted[i-1] {
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderRequestID carries request id between clients, proxies and the server
+const HeaderRequestID = echo.HeaderXRequestID
+
+type contextKey struct{}
+
+var level = new(slog.LevelVar)
+
+// Init configures the default logger, format is "json" or "text"
+func Init(format string, debug bool) {
+	SetDebug(debug)
+	slog.SetDefault(slog.New(newHandler(os.Stderr, format)))
+	// plain log calls from dependencies end up in the same output
+	log.SetFlags(0)
+}
+
+// SetDebug switches between debug and info level without recreating the logger
+func SetDebug(debug bool) {
+	if debug {
+		level.Set(slog.LevelDebug)
+	} else {
+		level.Set(slog.LevelInfo)
+	}
+}
+
+func newHandler(w io.Writer, format string) slog.Handler {
+	options := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(strings.TrimSpace(format), "text") {
+		return slog.NewTextHandler(w, options)
+	}
+	return slog.NewJSONHandler(w, options)
+}
+
+// WithRequestID stores request id in ctx
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// RequestID returns request id from ctx, empty outside of a request
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the default logger with request_id attached when present
+func FromContext(ctx context.Context) *slog.Logger {
+	if requestID := RequestID(ctx); requestID != "" {
+		return slog.Default().With("request_id", requestID)
+	}
+	return slog.Default()
+}
+
+// FromEcho returns request scoped logger
+func FromEcho(c echo.Context) *slog.Logger {
+	return FromContext(c.Request().Context())
+}
+
+// EchoRequestID returns request id of echo request
+func EchoRequestID(c echo.Context) string {
+	return RequestID(c.Request().Context())
+}
+
+// Middleware assigns request id (kept from X-Request-ID header when valid) and writes access log
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			request := c.Request()
+			requestID := request.Header.Get(HeaderRequestID)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+			c.SetRequest(request.WithContext(WithRequestID(request.Context(), requestID)))
+			c.Response().Header().Set(HeaderRequestID, requestID)
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			attrs := []any{
+				"request_id", requestID,
+				"method", request.Method,
+				"route", c.Path(),
+				"uri", request.RequestURI,
+				"status", status,
+				"latency_ms", time.Since(start).Milliseconds(),
+				"remote_ip", c.RealIP(),
+				"bytes_out", c.Response().Size,
+			}
+			if err != nil {
+				attrs = append(attrs, "error", err.Error())
+			}
+			switch {
+			case status >= 500:
+				slog.Error("request", attrs...)
+			case status >= 400:
+				slog.Warn("request", attrs...)
+			default:
+				slog.Info("request", attrs...)
+			}
+			return nil
+		}
+	}
+}
+
+// validRequestID accepts short printable ids from upstream proxies
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
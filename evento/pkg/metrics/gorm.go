@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startedAtKey = "metrics:started_at"
+
+// RegisterGormCallbacks measures every query executed through db
+func RegisterGormCallbacks(db *gorm.DB) error {
+	type processor struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}
+	processors := []processor{
+		{"create", db.Callback().Create().Before("gorm:create").Register, db.Callback().Create().After("gorm:create").Register},
+		{"query", db.Callback().Query().Before("gorm:query").Register, db.Callback().Query().After("gorm:query").Register},
+		{"update", db.Callback().Update().Before("gorm:update").Register, db.Callback().Update().After("gorm:update").Register},
+		{"delete", db.Callback().Delete().Before("gorm:delete").Register, db.Callback().Delete().After("gorm:delete").Register},
+		{"row", db.Callback().Row().Before("gorm:row").Register, db.Callback().Row().After("gorm:row").Register},
+		{"raw", db.Callback().Raw().Before("gorm:raw").Register, db.Callback().Raw().After("gorm:raw").Register},
+	}
+	for _, p := range processors {
+		operation := p.operation
+		if err := p.before("metrics:before_"+operation, startTimer); err != nil {
+			return err
+		}
+		if err := p.after("metrics:after_"+operation, func(tx *gorm.DB) {
+			observeQuery(tx, operation)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startTimer(tx *gorm.DB) {
+	tx.InstanceSet(startedAtKey, time.Now())
+}
+
+func observeQuery(tx *gorm.DB, operation string) {
+	value, ok := tx.InstanceGet(startedAtKey)
+	if !ok {
+		return
+	}
+	started, ok := value.(time.Time)
+	if !ok {
+		return
+	}
+	table := tx.Statement.Table
+	if table == "" {
+		table = "unknown"
+	}
+	dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(started).Seconds())
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		dbQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "evento"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	gateScans = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gate_scans_total",
+		Help:      "Member scans at gates by verdict.",
+	}, []string{"gate", "verdict"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query time by operation and table.",
+		Buckets:   []float64{0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Failed database queries by operation and table, not found excluded.",
+	}, []string{"operation", "table"})
+
+	smtpSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "smtp_sends_total",
+		Help:      "Emails sent by template and result.",
+	}, []string{"template", "result"})
+
+	aiCalls = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_assistant_call_duration_seconds",
+		Help:      "AI assistant calls by stage and result.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"stage", "result"})
+
+	freezeSchedulerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "freeze_scheduler_runs_total",
+		Help:      "Company freeze scheduler runs by result.",
+	}, []string{"result"})
+
+	freezeSchedulerApplied = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "freeze_scheduler_applied_total",
+		Help:      "Scheduled freeze actions applied to users.",
+	}, []string{"action"})
+
+	freezeSchedulerLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "freeze_scheduler_last_run_timestamp_seconds",
+		Help:      "Unix time of the last company freeze scheduler run.",
+	})
+
+	jobRuns = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_run_duration_seconds",
+		Help:      "Background job runs by job and result.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300},
+	}, []string{"job", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		gateScans,
+		dbQueryDuration,
+		dbQueryErrors,
+		smtpSends,
+		aiCalls,
+		freezeSchedulerRuns,
+		freezeSchedulerApplied,
+		freezeSchedulerLastRun,
+		jobRuns,
+	)
+}
+
+// Handler serves metrics in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records request latency labeled by the route pattern, not the raw path
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			status := c.Response().Status
+			if err != nil {
+				if httpErr, ok := err.(*echo.HTTPError); ok {
+					status = httpErr.Code
+				} else if !c.Response().Committed {
+					status = http.StatusInternalServerError
+				}
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			httpRequestDuration.WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// Result converts an error to a result label
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObserveGateScan counts a scan at gate with verdict like allowed, blocked, not_found
+func ObserveGateScan(gate, verdict string) {
+	gateScans.WithLabelValues(gate, verdict).Inc()
+}
+
+// ObserveSMTPSend counts an email send attempt
+func ObserveSMTPSend(template string, ok bool) {
+	result := "success"
+	if !ok {
+		result = "error"
+	}
+	smtpSends.WithLabelValues(template, result).Inc()
+}
+
+// ObserveAICall records duration of an AI assistant stage (generate, query)
+func ObserveAICall(stage string, started time.Time, err error) {
+	aiCalls.WithLabelValues(stage, Result(err)).Observe(time.Since(started).Seconds())
+}
+
+// ObserveFreezeSchedulerRun records a freeze scheduler run and applied actions
+func ObserveFreezeSchedulerRun(applied map[string]int, err error) {
+	freezeSchedulerRuns.WithLabelValues(Result(err)).Inc()
+	freezeSchedulerLastRun.SetToCurrentTime()
+	for action, count := range applied {
+		freezeSchedulerApplied.WithLabelValues(action).Add(float64(count))
+	}
+}
+
+// ObserveJobRun records a finished background job run
+func ObserveJobRun(job string, started time.Time, err error) {
+	jobRuns.WithLabelValues(job, Result(err)).Observe(time.Since(started).Seconds())
+}
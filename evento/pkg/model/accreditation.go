@@ -1,14 +1,26 @@
 package model
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 // Accreditation model, info about accreditation which is allowed to be on event
 type Accreditation struct {
 	Model
-	Name         string   `json:"name" gorm:"unique"`
-	ShortName    string   `json:"short_name"`
-	Description  string   `json:"description"`
-	Position     uint     `json:"position"`
-	Hidden       bool     `json:"hidden"`
-	RequirePhoto bool     `json:"require_photo"`
-	Gates        []Gate   `json:"gates" gorm:"many2many:accreditation_gates;"`
-	Members      []Member `json:"members" gorm:"foreignkey:AccreditationID"`
+	Name         string `json:"name" gorm:"unique"`
+	ShortName    string `json:"short_name"`
+	Description  string `json:"description"`
+	Position     uint   `json:"position"`
+	Hidden       bool   `json:"hidden"`
+	RequirePhoto bool   `json:"require_photo"`
+	// Capacity is the physical capacity of the zone, 0 means unlimited
+	Capacity uint `json:"capacity"`
+	// BadgeTemplateID is the badge of the accreditation, nil uses the default template
+	BadgeTemplateID *uuid.UUID `gorm:"type:uuid" json:"badge_template_id"`
+	// RegistrationDeadline closes adding and editing members of the accreditation for companies
+	RegistrationDeadline *time.Time `json:"registration_deadline"`
+	Gates                []Gate     `json:"gates" gorm:"many2many:accreditation_gates;"`
+	Members              []Member   `json:"members" gorm:"foreignkey:AccreditationID"`
 }
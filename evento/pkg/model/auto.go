@@ -24,4 +24,5 @@ type AutoHistory struct {
 	UserID     uuid.UUID `gorm:"type:uuid" json:"user_id"`
 	Details    string    `json:"details"`
 	ChangeType string    `json:"change_type"`
+	RequestID  string    `gorm:"index" json:"request_id"`
 }
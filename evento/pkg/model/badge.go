@@ -1,9 +1,32 @@
 package model
 
+import "github.com/google/uuid"
+
 // BadgeTemplate stores a JSON configuration for a badge layout.
 type BadgeTemplate struct {
 	Model
 	Name         string `json:"name" gorm:"unique"`
 	TemplateJSON string `json:"template_json" gorm:"type:text"`
 	IsDefault    bool   `json:"is_default"` // To select which template to use by default
+	Version      uint   `json:"version"`
+}
+
+// BadgeTemplateVersion keeps every saved revision of a badge template for rollback
+type BadgeTemplateVersion struct {
+	Model
+	TemplateID   uuid.UUID `gorm:"type:uuid;index" json:"template_id"`
+	Version      uint      `json:"version"`
+	Name         string    `json:"name"`
+	TemplateJSON string    `json:"template_json" gorm:"type:text"`
+	UserID       uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	Comment      string    `json:"comment"`
+}
+
+// CompanyBadgeTemplate replaces the badge template for members of one company,
+// AccreditationID uuid.Nil applies to every accreditation of the company
+type CompanyBadgeTemplate struct {
+	Model
+	CompanyID       uuid.UUID `gorm:"type:uuid;index" json:"company_id"`
+	AccreditationID uuid.UUID `gorm:"type:uuid" json:"accreditation_id"`
+	BadgeTemplateID uuid.UUID `gorm:"type:uuid" json:"badge_template_id"`
 }
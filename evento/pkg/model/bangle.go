@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BangleType is a kind of wristband, limited to an accreditation and an event when they are set
+type BangleType struct {
+	Model
+	Name            string     `json:"name" gorm:"unique"`
+	Color           string     `json:"color"`
+	Description     string     `json:"description"`
+	AccreditationID *uuid.UUID `gorm:"type:uuid" json:"accreditation_id"`
+	EventID         *uuid.UUID `gorm:"type:uuid" json:"event_id"`
+}
+
+// BanglePoint is a place where bangles are issued
+type BanglePoint struct {
+	Model
+	Name        string `json:"name" gorm:"unique"`
+	Description string `json:"description"`
+}
+
+// BangleStock is a delivery of bangles to an issuing point, negative quantity writes bangles off
+type BangleStock struct {
+	Model
+	PointID  uuid.UUID `gorm:"type:uuid;index" json:"point_id"`
+	TypeID   uuid.UUID `gorm:"type:uuid;index" json:"type_id"`
+	Quantity int       `json:"quantity"`
+	UserID   uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	Comment  string    `json:"comment"`
+}
+
+// BangleIssue is a bangle given to a member, the uid of the bangle is scanned at gates like the barcode
+type BangleIssue struct {
+	Model
+	MemberID uuid.UUID  `gorm:"type:uuid;index" json:"member_id"`
+	TypeID   *uuid.UUID `gorm:"type:uuid;index" json:"type_id"`
+	PointID  *uuid.UUID `gorm:"type:uuid;index" json:"point_id"`
+	// UID is the serial number or the NFC uid of the bangle
+	UID      string    `gorm:"index" json:"uid"`
+	UserID   uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	IssuedAt time.Time `json:"issued_at"`
+	// ReplacedAt is set when the member gets another bangle of the same type, the old uid stops passing gates
+	ReplacedAt *time.Time `json:"replaced_at"`
+}
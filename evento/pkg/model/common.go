@@ -8,14 +8,6 @@ import (
 	"gorm.io/gorm"
 )
 
-type Flags struct {
-	AddUser        bool   `json:"addUser"`
-	ShowYamlStruct bool   `json:"showYamlStruct"`
-	Migrate        bool   `json:"migrate"`
-	DropTable      bool   `json:"drop"`
-	Port           string `json:"port"`
-}
-
 type Model struct {
 	ID        uuid.UUID      `gorm:"type:uuid;primary_key;" json:"id"`
 	CreatedAt time.Time      `json:"-"`
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // CompanyGateLimit ...
 type CompanyGateLimit struct {
@@ -26,6 +30,16 @@ type CompanyEventLimit struct {
 	Limit     uint      `json:"limit"`
 }
 
+// CompanyDeadlineOverride replaces the registration deadline of an accreditation or event for one company,
+// nil Deadline keeps registration open
+type CompanyDeadlineOverride struct {
+	Model
+	CompanyID       uuid.UUID  `gorm:"type:uuid;index" json:"company_id"`
+	AccreditationID uuid.UUID  `gorm:"type:uuid" json:"accreditation_id"`
+	EventID         uuid.UUID  `gorm:"type:uuid" json:"event_id"`
+	Deadline        *time.Time `json:"deadline"`
+}
+
 // CompanyIn  - input model
 type CompanyIn struct {
 	Name                string          `json:"name" sql:"COLLATE NOCASE"`
@@ -57,6 +71,7 @@ type Company struct {
 	EditorID            uuid.UUID                   `gorm:"type:uuid" json:"editor_id"`
 	Phone               string                      `json:"phone"`
 	Email               string                      `json:"email"`
+	LimitsLocked        bool                        `json:"limits_locked"`
 	User                User                        `json:"user"`
 	Autos               []Auto                      `json:"autos" gorm:"foreignkey:CompanyID"`
 	Members             []Member                    `json:"members" gorm:"foreignkey:CompanyID"`
@@ -89,4 +104,5 @@ type CompanyHistory struct {
 	UserID     uuid.UUID `gorm:"type:uuid" json:"user_id"`
 	Details    string    `json:"details"`
 	ChangeType string    `json:"change_type"`
+	RequestID  string    `gorm:"index" json:"request_id"`
 }
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// CredentialKey is an Ed25519 key signing member credentials, only the active key signs,
+// retired keys stay published until they are deleted so issued credentials keep verifying,
+// PrivateKey is sealed with AES-GCM under a key derived from secret_jwt
+type CredentialKey struct {
+	Model
+	KeyID      string     `json:"key_id" gorm:"uniqueIndex"`
+	PublicKey  string     `json:"public_key"`
+	PrivateKey string     `json:"-"`
+	Active     bool       `json:"active"`
+	RetiredAt  *time.Time `json:"retired_at"`
+}
@@ -10,4 +10,8 @@ type Event struct {
 	Position    uint      `json:"position"`
 	TimeStart   time.Time `json:"time_start"`
 	TimeEnd     time.Time `json:"time_end"`
+	// Capacity is the physical capacity of the event day, 0 means unlimited
+	Capacity uint `json:"capacity"`
+	// RegistrationDeadline closes assigning the event to members for companies
+	RegistrationDeadline *time.Time `json:"registration_deadline"`
 }
@@ -0,0 +1,12 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// FrontendSettingsOverride stores admin-edited frontend settings, a single row replaces yaml values.
+type FrontendSettingsOverride struct {
+	Model
+	SettingsJSON string    `json:"-" gorm:"type:text"`
+	UpdatedByID  uuid.UUID `json:"updated_by_id" gorm:"type:uuid"`
+}
@@ -3,13 +3,16 @@ package model
 // Gate model, includes
 type Gate struct {
 	Model
-	Name           string          `json:"name"`
-	ShortName      string          `json:"short_name"`
-	Description    string          `json:"description"`
-	Position       uint            `json:"position"`
-	External       bool            `json:"external"`
-	Additional     bool            `json:"additional"`
-	RequirePhoto   bool            `json:"require_photo"`
+	Name         string `json:"name"`
+	ShortName    string `json:"short_name"`
+	Description  string `json:"description"`
+	Position     uint   `json:"position"`
+	External     bool   `json:"external"`
+	Additional   bool   `json:"additional"`
+	RequirePhoto bool   `json:"require_photo"`
+	Closed       bool   `json:"closed"`
+	// Capacity is the physical capacity of the gate zone, 0 means unlimited
+	Capacity       uint            `json:"capacity"`
 	Accreditations []Accreditation `json:"accreditations" gorm:"many2many:accreditation_gates;"`
 }
 
@@ -20,4 +23,5 @@ type GateIn struct {
 	Position    uint   `json:"position"`
 	External    bool   `json:"external"`
 	Additional  bool   `json:"additional"`
+	Capacity    uint   `json:"capacity"`
 }
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LimitChangeRequest is a company request to raise its accreditation, event or gate limits
+type LimitChangeRequest struct {
+	Model
+	CompanyID       uuid.UUID                `gorm:"type:uuid;index" json:"company_id"`
+	RequestedByID   uuid.UUID                `gorm:"type:uuid" json:"requested_by_id"`
+	Status          string                   `gorm:"index" json:"status"`
+	Justification   string                   `gorm:"type:text" json:"justification"`
+	DecisionComment string                   `gorm:"type:text" json:"decision_comment"`
+	DecidedByID     uuid.UUID                `gorm:"type:uuid" json:"decided_by_id"`
+	DecidedAt       *time.Time               `json:"decided_at,omitempty"`
+	SubmittedAt     time.Time                `json:"submitted_at"`
+	Items           []LimitChangeRequestItem `gorm:"foreignkey:RequestID" json:"items"`
+}
+
+// LimitChangeRequestItem is one limit row of a request, Kind is accreditation, event or gate
+type LimitChangeRequestItem struct {
+	Model
+	RequestID      uuid.UUID `gorm:"type:uuid;index" json:"request_id"`
+	Kind           string    `json:"kind"`
+	TargetID       uuid.UUID `gorm:"type:uuid" json:"target_id"`
+	CurrentLimit   uint      `json:"current_limit"`
+	RequestedLimit uint      `json:"requested_limit"`
+	ApprovedLimit  uint      `json:"approved_limit"`
+}
@@ -56,10 +56,69 @@ type MemberPass struct {
 	GateID   uuid.UUID `gorm:"type:uuid" json:"gate_id"`
 }
 
-// MemberPrint - log member enters
+// MemberPrint - log member badge prints
 type MemberPrint struct {
 	Model
-	MemberID uuid.UUID `gorm:"type:uuid" json:"member_id"`
+	MemberID   uuid.UUID  `gorm:"type:uuid" json:"member_id"`
+	PrintJobID uuid.UUID  `gorm:"type:uuid;index" json:"print_job_id"`
+	OperatorID uuid.UUID  `gorm:"type:uuid" json:"operator_id"`
+	Device     string     `json:"device"`
+	Printer    string     `json:"printer"`
+	TemplateID *uuid.UUID `gorm:"type:uuid" json:"template_id"`
+	Reprint    bool       `json:"reprint"`
+	// Reason of a reprint: lost, damaged or data_changed
+	Reason string `json:"reason"`
+	// InvalidatedBarcode is the barcode replaced on a reprint for loss
+	InvalidatedBarcode string `json:"invalidated_barcode,omitempty"`
+	Overridden         bool   `json:"overridden"`
+}
+
+// PrintOverrideApproval lets operators print up to MaxCount first badges of the company over its
+// in-event limit until ExpiresAt, Used counts the prints it has allowed
+type PrintOverrideApproval struct {
+	Model
+	AdminID   uuid.UUID `gorm:"type:uuid" json:"admin_id"`
+	CompanyID uuid.UUID `gorm:"type:uuid;index" json:"company_id"`
+	MaxCount  uint      `json:"max_count"`
+	Used      uint      `json:"used"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MemberBarcode is a barcode issued to a member, replaced barcodes stay as revoked
+// so scanners can tell them from forged ones
+type MemberBarcode struct {
+	Model
+	MemberID  uuid.UUID  `gorm:"type:uuid;index" json:"member_id"`
+	Barcode   string     `gorm:"index" json:"barcode"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	// RevokeReason is lost or regenerated
+	RevokeReason string `json:"revoke_reason,omitempty"`
+}
+
+// Member credential types and statuses
+const (
+	CredentialBarcode = "barcode"
+	CredentialNFC     = "nfc"
+	CredentialBangle  = "bangle"
+
+	CredentialActive    = "active"
+	CredentialSuspended = "suspended"
+	CredentialRevoked   = "revoked"
+)
+
+// MemberCredential is a code a member passes gates with besides the barcode: an nfc card, a bangle uid or an extra barcode,
+// unbound credentials stay as revoked
+type MemberCredential struct {
+	Model
+	MemberID   uuid.UUID  `gorm:"type:uuid;index" json:"member_id"`
+	Type       string     `json:"type"`
+	Value      string     `gorm:"index" json:"value"`
+	Status     string     `gorm:"index" json:"status"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	BoundAt    time.Time  `json:"bound_at"`
+	UnboundAt  *time.Time `json:"unbound_at"`
 }
 
 type MemberHistory struct {
@@ -68,4 +127,5 @@ type MemberHistory struct {
 	UserID     uuid.UUID `gorm:"type:uuid" json:"user_id"`
 	Details    string    `json:"details"`
 	ChangeType string    `json:"change_type"`
+	RequestID  string    `gorm:"index" json:"request_id"`
 }
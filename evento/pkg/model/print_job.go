@@ -0,0 +1,17 @@
+package model
+
+import "github.com/google/uuid"
+
+// PrintJob is one print or mass print request of an operator
+type PrintJob struct {
+	Model
+	OperatorID uuid.UUID     `gorm:"type:uuid;index" json:"operator_id"`
+	Device     string        `json:"device"`
+	Printer    string        `json:"printer"`
+	TemplateID *uuid.UUID    `gorm:"type:uuid" json:"template_id"`
+	Reason     string        `json:"reason"`
+	Total      int           `json:"total"`
+	Printed    int           `json:"printed"`
+	Rejected   int           `json:"rejected"`
+	Prints     []MemberPrint `gorm:"foreignkey:PrintJobID" json:"prints,omitempty"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledActionTarget selects objects a scheduled action applies to
+type ScheduledActionTarget struct {
+	All              bool        `json:"all,omitempty"`
+	CompanyIDs       []uuid.UUID `json:"company_ids,omitempty"`
+	AccreditationIDs []uuid.UUID `json:"accreditation_ids,omitempty"`
+	GateIDs          []uuid.UUID `json:"gate_ids,omitempty"`
+	Emails           []string    `json:"emails,omitempty"`
+}
+
+// ScheduledActionParams holds action specific parameters
+type ScheduledActionParams struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+	Report  string `json:"report,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ScheduledAction is a timed operation executed by the scheduler
+type ScheduledAction struct {
+	Model
+	Action      string                `json:"action" gorm:"index"`
+	Target      ScheduledActionTarget `json:"target" gorm:"serializer:json"`
+	Params      ScheduledActionParams `json:"params" gorm:"serializer:json"`
+	ExecuteAt   time.Time             `json:"execute_at" gorm:"index"`
+	Status      string                `json:"status" gorm:"index"`
+	Result      string                `json:"result" gorm:"type:text"`
+	ExecutedAt  *time.Time            `json:"executed_at,omitempty"`
+	CreatedByID uuid.UUID             `gorm:"type:uuid" json:"created_by_id"`
+}
+
+// ScheduledActionIn - input model
+type ScheduledActionIn struct {
+	Action    string                `json:"action"`
+	Target    ScheduledActionTarget `json:"target"`
+	Params    ScheduledActionParams `json:"params"`
+	ExecuteAt string                `json:"execute_at"`
+}
@@ -4,12 +4,14 @@ type (
 	SiteSettings struct {
 		DBPath             string   `yaml:"db_path"`
 		Debug              bool     `yaml:"debug"`
+		LogFormat          string   `yaml:"log_format"`
 		AdminToken         string   `yaml:"admin_token"`
 		SecretJWT          string   `yaml:"secret_jwt"`
 		PhotoStoragePath   string   `yaml:"photo_storage_path"`
 		CORSAllowOrigins   []string `yaml:"cors_allow_origins"`
 		AuthRateLimitRPS   int      `yaml:"auth_rate_limit_rps"`
 		AuthRateLimitBurst int      `yaml:"auth_rate_limit_burst"`
+		MetricsToken       string   `yaml:"metrics_token"`
 	}
 	MailSettings struct {
 		FromName string `yaml:"from_name"`
@@ -62,11 +64,32 @@ type (
 		QueryTimeoutMS     int     `yaml:"query_timeout_ms" json:"queryTimeoutMs"`
 		MaxRows            int     `yaml:"max_rows" json:"maxRows"`
 	}
+	BadgeSettings struct {
+		FontsDir    string  `yaml:"fonts_dir" json:"fontsDir"`
+		DefaultFont string  `yaml:"default_font" json:"defaultFont"`
+		PageSize    string  `yaml:"page_size" json:"pageSize"`
+		BadgeWidth  float64 `yaml:"badge_width" json:"badgeWidth"`
+		BadgeHeight float64 `yaml:"badge_height" json:"badgeHeight"`
+		Margin      float64 `yaml:"margin" json:"margin"`
+		Gap         float64 `yaml:"gap" json:"gap"`
+	}
+	PhotoSettings struct {
+		// AspectWidth:AspectHeight is the aspect ratio photos are cropped to
+		AspectWidth   int `yaml:"aspect_width" json:"aspectWidth"`
+		AspectHeight  int `yaml:"aspect_height" json:"aspectHeight"`
+		MaxWidth      int `yaml:"max_width" json:"maxWidth"`
+		Quality       int `yaml:"quality" json:"quality"`
+		ThumbnailSize int `yaml:"thumbnail_size" json:"thumbnailSize"`
+		// FaceCheck is none or skin, a skin tone heuristic which is not a face detector
+		FaceCheck string `yaml:"face_check" json:"faceCheck"`
+	}
 	AppSettings struct {
 		SiteSettings        `yaml:"site_settings"`
 		MailSettings        `yaml:"mail_settings"`
 		FrontendSettings    `yaml:"frontend_settings"`
 		ReportSettings      `yaml:"report_settings"`
 		AIAssistantSettings `yaml:"ai_assistant"`
+		BadgeSettings       `yaml:"badge_settings"`
+		PhotoSettings       `yaml:"photo_settings"`
 	}
 )
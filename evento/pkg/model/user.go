@@ -24,6 +24,8 @@ type User struct {
 	Frozen                 bool       `json:"frozen"`
 	FrozenAt               *time.Time `json:"frozen_at,omitempty"`
 	FrozenAction           string     `json:"frozen_action,omitempty"`
+	FrozenReason           string     `json:"frozen_reason,omitempty"`
+	FreezeExempt           bool       `json:"freeze_exempt"`
 	PasswordResetTokenHash string     `gorm:"index" json:"-"`
 	PasswordResetExpiresAt *time.Time `json:"-"`
 	CompanyID              uuid.UUID  `gorm:"type:uuid" json:"company_id"`
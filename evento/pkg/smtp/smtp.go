@@ -2,15 +2,18 @@ package smtp
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
-	"log"
+	"io"
 	"net/url"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
+	"github.com/eugenetolok/evento/pkg/logger"
+	"github.com/eugenetolok/evento/pkg/metrics"
 	"github.com/eugenetolok/evento/pkg/model"
 	"gopkg.in/gomail.v2"
 )
@@ -146,7 +149,7 @@ func ValidateTemplateContent(key, subject, body string) error {
 }
 
 // SendCreds sends tickets to user
-func SendCreds(email, password string, user model.User) bool {
+func SendCreds(ctx context.Context, email, password string, user model.User) bool {
 	baseURL := normalizeDomainURL(mailSettings.Domain)
 	data := map[string]interface{}{
 		"Username": user.Username,
@@ -158,11 +161,13 @@ func SendCreds(email, password string, user model.User) bool {
 
 	templateDef, ok := resolveManagedTemplate(TemplateKeyCredentials)
 	if !ok {
+		metrics.ObserveSMTPSend(TemplateKeyCredentials, false)
 		return false
 	}
 	subject, body, err := renderManagedTemplate(templateDef, data)
 	if err != nil {
-		log.Print("template rendering error: ", err)
+		logger.FromContext(ctx).Error("email template rendering failed", "template", TemplateKeyCredentials, "error", err)
+		metrics.ObserveSMTPSend(TemplateKeyCredentials, false)
 		return false
 	}
 
@@ -175,14 +180,17 @@ func SendCreds(email, password string, user model.User) bool {
 	// Send the email to Bob
 	d := gomail.NewDialer(mailSettings.SMTP, mailSettings.Port, mailSettings.User, mailSettings.Password)
 	if err := d.DialAndSend(m); err != nil {
-		log.Println("email didn't send to: " + email)
+		logger.FromContext(ctx).Error("credentials email was not sent", "email", email, "error", err)
+		metrics.ObserveSMTPSend(TemplateKeyCredentials, false)
 		return false
 	}
+	metrics.ObserveSMTPSend(TemplateKeyCredentials, true)
+	logger.FromContext(ctx).Info("credentials email sent", "email", email)
 	return true
 }
 
 // SendPasswordResetLink sends a one-time password reset link.
-func SendPasswordResetLink(email string, user model.User, resetToken string, expiresAt time.Time) bool {
+func SendPasswordResetLink(ctx context.Context, email string, user model.User, resetToken string, expiresAt time.Time) bool {
 	baseURL := normalizeDomainURL(mailSettings.Domain)
 	resetURL := fmt.Sprintf("%s/reset-password?token=%s", baseURL, url.QueryEscape(resetToken))
 
@@ -197,11 +205,13 @@ func SendPasswordResetLink(email string, user model.User, resetToken string, exp
 
 	templateDef, ok := resolveManagedTemplate(TemplateKeyResetPassword)
 	if !ok {
+		metrics.ObserveSMTPSend(TemplateKeyResetPassword, false)
 		return false
 	}
 	subject, body, err := renderManagedTemplate(templateDef, data)
 	if err != nil {
-		log.Print("template rendering error: ", err)
+		logger.FromContext(ctx).Error("email template rendering failed", "template", TemplateKeyResetPassword, "error", err)
+		metrics.ObserveSMTPSend(TemplateKeyResetPassword, false)
 		return false
 	}
 
@@ -213,13 +223,48 @@ func SendPasswordResetLink(email string, user model.User, resetToken string, exp
 
 	d := gomail.NewDialer(mailSettings.SMTP, mailSettings.Port, mailSettings.User, mailSettings.Password)
 	if err := d.DialAndSend(m); err != nil {
-		log.Println("reset email didn't send to: " + email)
+		logger.FromContext(ctx).Error("password reset email was not sent", "email", email, "error", err)
+		metrics.ObserveSMTPSend(TemplateKeyResetPassword, false)
 		return false
 	}
+	metrics.ObserveSMTPSend(TemplateKeyResetPassword, true)
+	logger.FromContext(ctx).Info("password reset email sent", "email", email)
 
 	return true
 }
 
+// Attachment is a file attached to an email
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// SendMail sends an html email with optional attachments, template label is used for metrics
+func SendMail(ctx context.Context, template, email, subject, body string, attachments ...Attachment) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", mailSettings.From)
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", strings.ReplaceAll(body, "APP_DOMAIN", mailSettings.Domain))
+	for _, attachment := range attachments {
+		data := attachment.Data
+		m.Attach(attachment.Name, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
+
+	d := gomail.NewDialer(mailSettings.SMTP, mailSettings.Port, mailSettings.User, mailSettings.Password)
+	if err := d.DialAndSend(m); err != nil {
+		logger.FromContext(ctx).Error("email was not sent", "template", template, "email", email, "error", err)
+		metrics.ObserveSMTPSend(template, false)
+		return err
+	}
+	metrics.ObserveSMTPSend(template, true)
+	logger.FromContext(ctx).Info("email sent", "template", template, "email", email)
+	return nil
+}
+
 func normalizeDomainURL(domain string) string {
 	value := strings.TrimSpace(domain)
 	if value == "" {
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/eugenetolok/evento/pkg/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CapacityUsage compares the physical capacity of an accreditation, event or gate
+// with the sum of company limits and registered members, zero capacity means unlimited
+type CapacityUsage struct {
+	Kind       string    `json:"kind"`
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Capacity   uint      `json:"capacity"`
+	Allocated  uint      `json:"allocated"`
+	Registered int64     `json:"registered"`
+}
+
+type capacityTarget struct {
+	title       string
+	model       interface{}
+	limits      interface{}
+	limitColumn string
+}
+
+var capacityKinds = []string{"accreditation", "event", "gate"}
+
+var capacityTargets = map[string]capacityTarget{
+	"accreditation": {title: "аккредитация", model: &model.Accreditation{}, limits: &model.CompanyAccreditationLimit{}, limitColumn: "accreditation_id"},
+	"event":         {title: "мероприятие", model: &model.Event{}, limits: &model.CompanyEventLimit{}, limitColumn: "event_id"},
+	"gate":          {title: "зона", model: &model.Gate{}, limits: &model.CompanyGateLimit{}, limitColumn: "gate_id"},
+}
+
+type capacityRow struct {
+	ID       uuid.UUID
+	Name     string
+	Capacity uint
+}
+
+// CheckAllocatedCapacity fails when setting the company limit would make the sum
+// of all company limits for the target exceed its capacity
+func CheckAllocatedCapacity(tx *gorm.DB, companyID uuid.UUID, kind string, targetID uuid.UUID, limit uint) error {
+	target, ok := capacityTargets[kind]
+	if !ok {
+		return fmt.Errorf("неизвестный тип лимита: %s", kind)
+	}
+	var row capacityRow
+	if err := tx.Model(target.model).Select("id", "name", "capacity").Where("id = ?", targetID).Take(&row).Error; err != nil {
+		return err
+	}
+	if row.Capacity == 0 {
+		return nil
+	}
+	allocated, err := allocatedCapacity(tx.Where("company_id <> ?", companyID), target, targetID)
+	if err != nil {
+		return err
+	}
+	if allocated+limit > row.Capacity {
+		return fmt.Errorf("превышена вместимость: %s «%s» вмещает %d, другим компаниям выделено %d, доступно %d",
+			target.title, row.Name, row.Capacity, allocated, subtractCapacity(row.Capacity, allocated))
+	}
+	return nil
+}
+
+// CheckRegisteredCapacity fails when one more member with the accreditation, events and gates
+// would exceed their capacity, memberID excludes the member being updated
+func CheckRegisteredCapacity(tx *gorm.DB, accreditationID uuid.UUID, eventIDs, gateIDs []uuid.UUID, memberID *uuid.UUID) error {
+	var accreditationGateIDs []uuid.UUID
+	if err := tx.Table("accreditation_gates").Where("accreditation_id = ?", accreditationID).Pluck("gate_id", &accreditationGateIDs).Error; err != nil {
+		return err
+	}
+	targets := map[string][]uuid.UUID{
+		"accreditation": {accreditationID},
+		"event":         eventIDs,
+		"gate":          append(accreditationGateIDs, gateIDs...),
+	}
+	for _, kind := range capacityKinds {
+		if len(targets[kind]) == 0 {
+			continue
+		}
+		var rows []capacityRow
+		if err := tx.Model(capacityTargets[kind].model).Select("id", "name", "capacity").
+			Where("id IN ? AND capacity > 0", targets[kind]).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			query := registeredQuery(tx, kind, row.ID)
+			if memberID != nil && *memberID != uuid.Nil {
+				query = query.Where("members.id <> ?", *memberID)
+			}
+			var registered int64
+			if err := query.Count(&registered).Error; err != nil {
+				return err
+			}
+			if registered+1 > int64(row.Capacity) {
+				return fmt.Errorf("достигнута вместимость: %s «%s» (%d)", capacityTargets[kind].title, row.Name, row.Capacity)
+			}
+		}
+	}
+	return nil
+}
+
+// CapacityUsages lists capacity, allocated limits and registered members of all accreditations, events and gates
+func CapacityUsages(tx *gorm.DB) ([]CapacityUsage, error) {
+	usages := []CapacityUsage{}
+	for _, kind := range capacityKinds {
+		target := capacityTargets[kind]
+		var rows []capacityRow
+		if err := tx.Model(target.model).Select("id", "name", "capacity").Order("position desc").Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			usage := CapacityUsage{Kind: kind, ID: row.ID, Name: row.Name, Capacity: row.Capacity}
+			var err error
+			if usage.Allocated, err = allocatedCapacity(tx, target, row.ID); err != nil {
+				return nil, err
+			}
+			if err = registeredQuery(tx, kind, row.ID).Count(&usage.Registered).Error; err != nil {
+				return nil, err
+			}
+			usages = append(usages, usage)
+		}
+	}
+	return usages, nil
+}
+
+func allocatedCapacity(tx *gorm.DB, target capacityTarget, targetID uuid.UUID) (uint, error) {
+	var limits []uint
+	if err := tx.Model(target.limits).Where(target.limitColumn+" = ?", targetID).Pluck("limit", &limits).Error; err != nil {
+		return 0, err
+	}
+	var allocated uint
+	for _, limit := range limits {
+		allocated += limit
+	}
+	return allocated, nil
+}
+
+// registeredQuery counts members of the target, gate members get it from their accreditation or as an additional gate
+func registeredQuery(tx *gorm.DB, kind string, targetID uuid.UUID) *gorm.DB {
+	query := tx.Model(&model.Member{})
+	switch kind {
+	case "accreditation":
+		return query.Where("members.accreditation_id = ?", targetID)
+	case "event":
+		return query.Where("members.id IN (?)", tx.Table("member_events").Select("member_id").Where("event_id = ?", targetID))
+	default:
+		return query.Where("(members.accreditation_id IN (?) OR members.id IN (?))",
+			tx.Table("accreditation_gates").Select("accreditation_id").Where("gate_id = ?", targetID),
+			tx.Table("member_gates").Select("member_id").Where("gate_id = ?", targetID))
+	}
+}
+
+func subtractCapacity(capacity, allocated uint) uint {
+	if allocated >= capacity {
+		return 0
+	}
+	return capacity - allocated
+}
+
+// CapacityConfigured reports whether any accreditation, event or gate has a capacity set
+func CapacityConfigured(tx *gorm.DB) bool {
+	for _, kind := range capacityKinds {
+		var count int64
+		if err := tx.Model(capacityTargets[kind].model).Where("capacity > 0").Count(&count).Error; err != nil || count > 0 {
+			return true
+		}
+	}
+	return false
+}
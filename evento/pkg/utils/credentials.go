@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"github.com/eugenetolok/evento/pkg/model"
+	"gorm.io/gorm"
+)
+
+// CredentialTaken reports whether the value is a bound member credential or the barcode of a member,
+// scanners resolve both so one value can not belong to two members
+func CredentialTaken(tx *gorm.DB, value string) (bool, error) {
+	var count int64
+	if err := tx.Model(&model.MemberCredential{}).Where("value = ? AND status <> ?", value, model.CredentialRevoked).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := tx.Model(&model.Member{}).Where("barcode = ?", value).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -38,3 +39,11 @@ func SHA256Hash(text string) string {
 	hash := sha256.Sum256([]byte(text))
 	return hex.EncodeToString(hash[:])
 }
+
+// DeriveKey derives a key for one purpose from the secret, so a leaked derived key does not expose the secret
+// or keys of other purposes: DeriveKey(secretJWT, "print-override")
+func DeriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("evento:" + label))
+	return mac.Sum(nil)
+}
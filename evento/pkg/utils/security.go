@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eugenetolok/evento/pkg/logger"
 	"github.com/eugenetolok/evento/pkg/model"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -153,25 +154,27 @@ func CheckUserWritePermission(c echo.Context, db *gorm.DB) bool {
 	var user model.User
 	if err := db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Println("!!!! URGENT: user not found!!!", err.Error())
+			logger.FromEcho(c).Error("write permission check: user not found", "user_id", userID, "error", err)
 			return false
 		}
-		fmt.Println("!!!! URGENT: some strange error!!!", err.Error())
+		logger.FromEcho(c).Error("write permission check failed", "user_id", userID, "error", err)
 		return false
 	}
 	// Apply delayed freeze/unfreeze for company users just-in-time.
 	// This keeps access state correct even if the scheduler tick has not run yet.
 	if user.Role == "company" && user.FrozenAt != nil && !user.FrozenAt.After(time.Now()) {
-		switch user.FrozenAction {
-		case "freeze":
+		switch {
+		case user.FrozenAction == "freeze" && !user.FreezeExempt:
 			user.Frozen = true
-		case "unfreeze":
+		case user.FrozenAction == "unfreeze":
 			user.Frozen = false
+			user.FrozenReason = ""
 		}
 		_ = db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
 			"frozen":        user.Frozen,
 			"frozen_action": "",
 			"frozen_at":     nil,
+			"frozen_reason": user.FrozenReason,
 		}).Error
 	}
 	return !user.Frozen
@@ -9,10 +9,19 @@ import (
 
 // Unmarshal yaml files
 func UnmarshalYaml(filename string, i interface{}) bool {
-	if FileNotExist(filepath.Join(WorkDir(), filename)) == nil {
-		file, _ := ioutil.ReadFile(filepath.Join(filepath.Join(WorkDir(), filename)))
+	path := ResolvePath(filename)
+	if FileNotExist(path) == nil {
+		file, _ := ioutil.ReadFile(path)
 		err := yaml.Unmarshal(file, i)
 		return err == nil
 	}
 	return false
 }
+
+// ResolvePath makes relative paths relative to the binary folder
+func ResolvePath(filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(WorkDir(), filename)
+}